@@ -3,7 +3,8 @@
 // line-by-line as MsgStdinChunk frames. The daemon shows the launcher
 // window as soon as Hello arrives — items appear progressively as the user
 // types. The daemon's MsgResponse signals completion; we print the
-// selection and exit.
+// selection and exit. With --read0 stdin is split on NUL instead, and
+// with --print0 the selection is printed as the daemon NUL-terminated it.
 //
 // Behavioral contract preserved from the previous standalone binary:
 //   - Selected item printed on stdout (one line, no trailing whitespace
@@ -29,7 +30,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/sam33r/goose-launcher/pkg/config"
 	"github.com/sam33r/goose-launcher/pkg/daemon"
+	"github.com/sam33r/goose-launcher/pkg/input"
 )
 
 const (
@@ -65,7 +68,8 @@ func main() {
 	// Forward stdin in the background; main goroutine blocks on the daemon's
 	// response. The forwarder exits cleanly on EOF (sends MsgStdinEOF) or on
 	// any write error (daemon closed the socket — that's the cancel path).
-	go forwardStdin(conn)
+	read0, print0 := config.ClientOptions(os.Args[1:])
+	go forwardStdin(conn, read0)
 
 	resp, err := daemon.ReadResponse(conn)
 	if err != nil {
@@ -77,12 +81,18 @@ func main() {
 		fmt.Fprintf(os.Stderr, "goose-launcher: %s\n", resp.Error)
 	}
 	if resp.Selection != "" {
-		fmt.Println(resp.Selection)
+		if print0 {
+			// Every item already ends in NUL.
+			fmt.Print(resp.Selection)
+		} else {
+			fmt.Println(resp.Selection)
+		}
 	}
 	os.Exit(resp.ExitCode)
 }
 
-// forwardStdin reads os.Stdin line by line and ships batches over conn.
+// forwardStdin reads os.Stdin line by line (NUL-terminated item by item
+// with read0) and ships batches over conn.
 // Batches flush when they hit chunkMaxLines, chunkMaxBytes, or chunkFlushIdle
 // has elapsed since the first line in the batch — the latter keeps slow
 // producers (one item per second) interactive.
@@ -90,10 +100,13 @@ func main() {
 // On stdin EOF, sends a MsgStdinEOF frame. On any write error (most commonly
 // the daemon closing the socket after the user picks an item), returns
 // silently — losing the rest of stdin is the desired cancel behavior.
-func forwardStdin(conn net.Conn) {
+func forwardStdin(conn net.Conn, read0 bool) {
 	scanner := bufio.NewScanner(os.Stdin)
 	// Allow long lines (default 64 KB cap is too low for some launcher inputs).
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	if read0 {
+		scanner.Split(input.ScanNul)
+	}
 
 	var batch []string
 	var batchBytes int
@@ -144,7 +157,7 @@ func forwardStdin(conn net.Conn) {
 				firstAt = time.Now()
 			}
 			batch = append(batch, msg.line)
-			batchBytes += len(msg.line) + 1 // +1 for the newline accounting
+			batchBytes += len(msg.line) + 1 // +1 for the terminator accounting
 			if len(batch) >= chunkMaxLines || batchBytes >= chunkMaxBytes {
 				if !flush() {
 					return
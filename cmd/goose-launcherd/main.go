@@ -28,8 +28,11 @@ import (
 
 	"github.com/sam33r/goose-launcher/pkg/config"
 	"github.com/sam33r/goose-launcher/pkg/daemon"
+	"github.com/sam33r/goose-launcher/pkg/history"
 	"github.com/sam33r/goose-launcher/pkg/input"
 	"github.com/sam33r/goose-launcher/pkg/macwin"
+	"github.com/sam33r/goose-launcher/pkg/matcher"
+	"github.com/sam33r/goose-launcher/pkg/ranker"
 	"github.com/sam33r/goose-launcher/pkg/ui"
 )
 
@@ -47,6 +50,11 @@ var (
 	// Single-flight: only one window onscreen at a time. Multiple clients
 	// queue here.
 	workMu sync.Mutex
+
+	// Selection history for --history-rank and the queries --learn
+	// learned, each loaded on first use. Guarded by workMu.
+	historyStore *history.Store
+	learnedStore *history.Learned
 )
 
 func main() {
@@ -222,6 +230,12 @@ func serveRequest(conn net.Conn, hello *daemon.Hello) {
 	workMu.Lock()
 	defer workMu.Unlock()
 
+	// --forget edits the learned queries and exits without a window.
+	if cfg.Forget != "" {
+		forgetLearned(conn, cfg.HistoryKey, cfg.Forget)
+		return
+	}
+
 	// Wait for bootstrap (window created + handle located + accessory
 	// applied). On the autostart path the client connects before bootstrap
 	// finishes; on subsequent requests this is already closed and returns
@@ -245,7 +259,34 @@ func serveRequest(conn net.Conn, hello *daemon.Hello) {
 		}
 	}()
 
+	m, err := newMatcher(cfg)
+	if err != nil {
+		writeResponseLogged(conn, &daemon.Response{ExitCode: 2, Error: err.Error()})
+		return
+	}
+
+	r, err := newRanker(cfg)
+	if err != nil {
+		writeResponseLogged(conn, &daemon.Response{ExitCode: 2, Error: err.Error()})
+		return
+	}
+
+	if cfg.HistoryRank {
+		if store := loadHistory(); store != nil {
+			r.History = store.Scores(cfg.HistoryKey, time.Now())
+		}
+	}
+	if cfg.Learn {
+		if store := loadLearned(); store != nil {
+			r.Learned = store.Knowledge(cfg.HistoryKey)
+		}
+	}
+
 	w.ConfigureEmpty(cfg.HighlightMatches, cfg.ExactMode, cfg.Rank, cfg.Multi)
+	w.SetMatcher(m)
+	w.SetRanker(r)
+	w.SetExplain(cfg.Explain)
+	w.SetPrint0(cfg.Print0)
 	log.Printf("serving streaming request")
 
 	t0 := time.Now()
@@ -268,7 +309,7 @@ func serveRequest(conn net.Conn, hello *daemon.Hello) {
 	// it sees MsgStdinEOF or a read error — the latter happens when we close
 	// the socket below after writing the response.
 	chunkReaderDone := make(chan int)
-	go streamChunks(conn, w, cfg.Markup, chunkReaderDone)
+	go streamChunks(conn, w, cfg.ParseOptions(), chunkReaderDone)
 
 	selected := w.WaitForSelection()
 
@@ -293,17 +334,155 @@ func serveRequest(conn net.Conn, hello *daemon.Hello) {
 		time.Since(t0).Seconds()*1000, streamedCount, selected)
 
 	h.OrderOut()
+
+	if accepted := w.AcceptedItems(); len(accepted) > 0 {
+		if cfg.HistoryRank {
+			recordHistory(cfg.HistoryKey, accepted)
+		}
+		if cfg.Learn {
+			learnQuery(cfg.HistoryKey, w.AcceptedQuery(), accepted)
+		}
+	}
+}
+
+// loadHistory returns the selection history, opening it on first use. A
+// history that can't be read is logged and ranking goes on without it.
+func loadHistory() *history.Store {
+	if historyStore == nil {
+		store, err := history.Open(daemon.CachePath(history.FileName))
+		if err != nil {
+			log.Printf("history: %v", err)
+			return nil
+		}
+		historyStore = store
+	}
+	return historyStore
+}
+
+// recordHistory remembers the accepted items under key and saves the
+// history. Runs after the window is hidden, so the write costs the user
+// nothing.
+func recordHistory(key string, items []input.Item) {
+	store := loadHistory()
+	if store == nil {
+		return
+	}
+	store.Record(key, items, time.Now())
+	if err := store.Save(); err != nil {
+		log.Printf("history: %v", err)
+	}
+}
+
+// loadLearned returns the learned queries, opening them on first use. Like
+// loadHistory it logs a file it can't read and carries on without.
+func loadLearned() *history.Learned {
+	if learnedStore == nil {
+		store, err := history.OpenLearned(daemon.CachePath(history.LearnedFileName))
+		if err != nil {
+			log.Printf("learned queries: %v", err)
+			return nil
+		}
+		learnedStore = store
+	}
+	return learnedStore
+}
+
+// learnQuery teaches context that items were accepted for query and saves
+// the learned queries.
+func learnQuery(context, query string, items []input.Item) {
+	store := loadLearned()
+	if store == nil {
+		return
+	}
+	store.Learn(context, query, items, time.Now())
+	if err := store.Save(); err != nil {
+		log.Printf("learned queries: %v", err)
+	}
+}
+
+// forgetLearned serves --forget: drops what context learned for query and
+// reports how many learned queries went.
+func forgetLearned(conn net.Conn, context, query string) {
+	store := loadLearned()
+	if store == nil {
+		writeResponseLogged(conn, &daemon.Response{ExitCode: 1, Error: "learned queries unreadable; see the daemon log"})
+		return
+	}
+	n := store.Forget(context, query)
+	if err := store.Save(); err != nil {
+		writeResponseLogged(conn, &daemon.Response{ExitCode: 1, Error: err.Error()})
+		return
+	}
+	log.Printf("forgot %d learned queries for %q in %q", n, query, context)
+	writeResponseLogged(conn, &daemon.Response{ExitCode: 0})
+}
+
+// newMatcher builds the per-request matcher from the parsed flags.
+// --matcher picks a registered matcher by name; otherwise --exact/--fuzzy
+// and --regex select the built-in mode.
+func newMatcher(cfg *config.Config) (matcher.Matcher, error) {
+	algo, err := matcher.ParseAlgo(cfg.Algo)
+	if err != nil {
+		return nil, err
+	}
+	caseMode, err := matcher.ParseCaseMode(cfg.Case)
+	if err != nil {
+		return nil, err
+	}
+	opts := matcher.Options{
+		Case:      caseMode,
+		Exact:     cfg.ExactMode,
+		Algo:      algo,
+		Extended:  cfg.Extended,
+		Normalize: !cfg.Literal,
+		Regex:     cfg.Regex,
+		Typos:     cfg.Typos,
+	}
+	if cfg.Matcher != "" {
+		return matcher.New(cfg.Matcher, opts)
+	}
+	return matcher.NewFuzzyMatcherWithOptions(opts), nil
+}
+
+// newRanker builds the per-request ranker: the --scheme's weights,
+// overridden by --ranker-config, then --weights and --tiebreak.
+func newRanker(cfg *config.Config) (*ranker.Ranker, error) {
+	r, err := ranker.NewScheme(cfg.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RankerConfig != "" {
+		// The daemon doesn't share the client's working directory.
+		if !filepath.IsAbs(cfg.RankerConfig) {
+			return nil, fmt.Errorf("--ranker-config needs an absolute path, got %q", cfg.RankerConfig)
+		}
+		if err := r.LoadConfig(cfg.RankerConfig); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Weights != "" {
+		if err := r.SetWeights(cfg.Weights); err != nil {
+			return nil, fmt.Errorf("--weights: %w", err)
+		}
+	}
+	if cfg.Tiebreak != "" {
+		if r.Tiebreak, err = ranker.ParseTiebreak(cfg.Tiebreak); err != nil {
+			return nil, fmt.Errorf("--tiebreak: %w", err)
+		}
+	}
+	return r, nil
 }
 
 // streamChunks reads MsgStdinChunk frames off conn and appends the parsed
 // items to w via AppendItems. Exits when:
-//   - MsgStdinEOF arrives (clean termination by client),
+//   - MsgStdinEOF arrives (clean termination by client; w.EndItems lets the
+//     window index the complete input),
 //   - any read error occurs (connection closed by daemon after selection,
 //     or client disconnected unexpectedly),
 //   - a frame with an unexpected tag arrives.
 //
 // Reports total items streamed via doneC, then closes it.
-func streamChunks(conn net.Conn, w *ui.Window, markupFormat string, doneC chan<- int) {
+func streamChunks(conn net.Conn, w *ui.Window, parseOpts input.ParseOptions, doneC chan<- int) {
 	defer close(doneC)
 	index := 0
 	for {
@@ -322,11 +501,12 @@ func streamChunks(conn net.Conn, w *ui.Window, markupFormat string, doneC chan<-
 			}
 			batch := make([]input.Item, 0, len(chunk.Lines))
 			for _, line := range chunk.Lines {
-				batch = append(batch, input.ParseLine(line, index, markupFormat))
+				batch = append(batch, input.ParseLineWithOptions(line, index, parseOpts))
 				index++
 			}
 			w.AppendItems(batch)
 		case daemon.MsgTagStdinEOF:
+			w.EndItems()
 			doneC <- index
 			return
 		default:
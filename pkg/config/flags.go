@@ -3,6 +3,10 @@ package config
 import (
 	"flag"
 	"fmt"
+	"io"
+	"os"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
 )
 
 // Config holds launcher configuration from CLI flags
@@ -11,37 +15,124 @@ type Config struct {
 	Rank             bool // Enable ranking/scoring of matches
 	Height           int
 	Layout           string
-	HighlightMatches bool   // Highlight matching text in results (default: true)
-	Markup           string // Stdin markup format: "" (off) or "pango"
-	Multi            bool   // Multi-select mode: Ctrl+Enter marks; Enter outputs all marks newline-joined
+	HighlightMatches bool               // Highlight matching text in results (default: true)
+	Markup           string             // Stdin markup format: "" (off) or "pango"
+	ANSI             bool               // Parse ANSI color escapes in stdin
+	Multi            bool               // Multi-select mode: Ctrl+Enter marks; Enter outputs all marks newline-joined
+	Algo             string             // Fuzzy alignment algorithm: "v1" (greedy, default) or "v2" (best-scoring)
+	Extended         bool               // fzf extended search syntax in the query (default: true)
+	Case             string             // Case sensitivity: "smart" (default), "ignore" (-i) or "respect" (+i)
+	Literal          bool               // Match diacritics literally instead of normalizing (default: false)
+	Regex            bool               // Treat the query as a regular expression (Ctrl+R toggles at runtime)
+	Matcher          string             // Registered matcher name (e.g. "fuzzy", "regex"); "" derives it from --exact/--fuzzy/--regex
+	Typos            int                // Edits allowed per query term for approximate matching (0-2; default: 0)
+	Delimiter        string             // Field delimiter regex for --nth/--with-nth; "" is AWK-style whitespace
+	Nth              []input.FieldRange // Fields matching is limited to; nil means the whole line
+	WithNth          []input.FieldRange // Fields displayed; nil means the whole line
+	Scheme           string             // Registered ranking scheme with --rank: "default", "path", "history" or a plugin's own
+	HistoryRank      bool               // Record accepted items and rank frequently/recently chosen ones first (implies --rank)
+	HistoryKey       string             // Namespace of the selection history (default: "default")
+	Learn            bool               // Learn which item is accepted for each query and rank it first next time (implies --rank)
+	Forget           string             // Forget what --learn learned for this query in the --history-key context, then exit
+	Tiebreak         string             // Comma-separated tiebreak chain: score, length, begin, end, index ("" keeps the scheme's)
+	Weights          string             // Comma-separated name=value overrides of the scheme's ranking weights
+	RankerConfig     string             // Ranker config file of weights and tiebreak, applied before --weights/--tiebreak
+	Explain          bool               // Print each accepted line's score breakdown as a tab-separated column; Ctrl+E overlays the cursor row's
+	Read0            bool               // Stdin items are NUL-terminated, not newline-terminated (find -print0); split by the client
+	Print0           bool               // Terminate each output item with NUL instead of joining them with newlines
 }
 
 // ParseFlags parses command-line arguments into Config
 func ParseFlags(args []string) (*Config, error) {
+	return parseFlags(args, os.Stderr)
+}
+
+// ClientOptions reports the flags the client acts on itself, as it reads
+// stdin and prints the selection: --read0 and --print0. Invalid arguments
+// report neither; the daemon parses them too and returns the error.
+func ClientOptions(args []string) (read0, print0 bool) {
+	cfg, err := parseFlags(args, io.Discard)
+	if err != nil {
+		return false, false
+	}
+	return cfg.Read0, cfg.Print0
+}
+
+// parseFlags is ParseFlags with usage and errors printed to output.
+func parseFlags(args []string, output io.Writer) (*Config, error) {
 	cfg := &Config{
 		ExactMode:        true, // Default: exact match mode (changed from false)
 		Rank:             false, // Default: preserve stdin order (no re-sorting)
 		Height:           100, // Default: full height
 		Layout:           "default",
 		HighlightMatches: true, // Default: highlight matches enabled
+		Algo:             "v1",
+		Extended:         false, // Default: the whole query is one term (-x opts in)
+		Case:             "smart",
+		Scheme:           "default",
+		HistoryKey:       "default",
 	}
 
 	fs := flag.NewFlagSet("goose-launcher", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	// fzf spells "case-sensitive" as +i, which the flag package would treat
+	// as the first positional argument (and stop parsing). Rewrite it to its
+	// long form before parsing.
+	args = rewritePlusFlags(args)
 
 	// Define flags
 	var fuzzy bool
 	var noSort bool
+	var noExtended bool
 	fs.BoolVar(&cfg.ExactMode, "e", true, "exact match mode (default: true)")
 	fs.BoolVar(&cfg.ExactMode, "exact", true, "exact match mode (default: true)")
 	fs.BoolVar(&fuzzy, "fuzzy", false, "fuzzy match mode (overrides --exact)")
 	fs.BoolVar(&cfg.Rank, "rank", false, "rank results by match quality (default: false)")
+	fs.StringVar(&cfg.Scheme, "scheme", "default", "ranking scheme with --rank: default, path (favor basename matches), history (favor input order) or a registered custom scheme")
+	fs.StringVar(&cfg.Tiebreak, "tiebreak", "", "comma-separated sort criteria for equally ranked matches: score, length, begin, end, index")
+	fs.StringVar(&cfg.Weights, "weights", "", "override ranking weights, e.g. compactness=40,early=20,position-span=500")
+	fs.StringVar(&cfg.RankerConfig, "ranker-config", "", "absolute path of a file of name = value ranking weights and tiebreak")
+	fs.BoolVar(&cfg.Explain, "explain", false, "print each accepted line with its score breakdown as a tab-separated column, and overlay the cursor row's (Ctrl+E toggles)")
+	fs.BoolVar(&cfg.HistoryRank, "history-rank", false, "remember accepted items and rank frequently and recently chosen ones first (implies --rank)")
+	fs.StringVar(&cfg.HistoryKey, "history-key", "default", "history namespace for --history-rank, e.g. the plugin name")
+	fs.BoolVar(&cfg.Learn, "learn", false, "learn which item you pick for each query and rank it first when you type the query again (implies --rank)")
+	fs.StringVar(&cfg.Forget, "forget", "", "forget the picks --learn learned for this query (and longer ones starting with it) in the --history-key context, then exit")
 	fs.BoolVar(&noSort, "no-sort", false, "filter only; preserve input order (default; kept for compatibility)")
+	fs.BoolVar(&cfg.Read0, "read0", false, "read NUL-terminated items from stdin instead of lines (e.g. find -print0)")
+	fs.BoolVar(&cfg.Print0, "print0", false, "terminate each output item with NUL instead of a newline")
 	fs.IntVar(&cfg.Height, "height", 100, "window height (percentage)")
 	fs.StringVar(&cfg.Layout, "layout", "default", "layout style (default|reverse)")
 	fs.BoolVar(&cfg.HighlightMatches, "highlight-matches", true, "highlight matching text in results")
 	fs.StringVar(&cfg.Markup, "markup", "", "stdin markup format: pango (default: off)")
+	fs.BoolVar(&cfg.ANSI, "ansi", false, "parse ANSI color escapes in stdin (e.g. git log --color)")
 	fs.BoolVar(&cfg.Multi, "m", false, "enable multi-select (Ctrl+Enter marks; Enter outputs all marked items)")
 	fs.BoolVar(&cfg.Multi, "multi", false, "enable multi-select (Ctrl+Enter marks; Enter outputs all marked items)")
+	fs.StringVar(&cfg.Algo, "algo", "v1", "fuzzy matching algorithm: v1 (greedy, fast) or v2 (best-scoring alignment)")
+	fs.BoolVar(&cfg.Extended, "x", false, "extended search syntax: AND terms, a | b, !not, ^prefix, suffix$, 'exact")
+	fs.BoolVar(&cfg.Extended, "extended", false, "extended search syntax: AND terms, a | b, !not, ^prefix, suffix$, 'exact")
+	fs.BoolVar(&noExtended, "no-extended", false, "treat the whole query as one literal term")
+	fs.BoolVar(&cfg.Regex, "regex", false, "treat the query as a regular expression (Ctrl+R toggles at runtime)")
+	fs.StringVar(&cfg.Matcher, "matcher", "", "matcher by name: exact, fuzzy, regex or a registered custom matcher (overrides --exact/--fuzzy/--regex)")
+	var nth, withNth string
+	fs.StringVar(&cfg.Delimiter, "d", "", "field delimiter regex for --nth/--with-nth (default: AWK-style whitespace)")
+	fs.StringVar(&cfg.Delimiter, "delimiter", "", "field delimiter regex for --nth/--with-nth (default: AWK-style whitespace)")
+	fs.StringVar(&nth, "n", "", "comma-separated field index expressions limiting the search scope")
+	fs.StringVar(&nth, "nth", "", "comma-separated field index expressions limiting the search scope")
+	fs.StringVar(&withNth, "with-nth", "", "comma-separated field index expressions choosing the displayed fields")
+	fs.IntVar(&cfg.Typos, "typos", 0, "also match items within 1 or 2 typos of each query term, ranked below exact hits (default: 0)")
+	fs.BoolVar(&cfg.Literal, "literal", false, "do not normalize diacritics and compatibility characters before matching")
+	// Case flags are order-sensitive like fzf's: the last one wins.
+	setCase := func(mode string) func(string) error {
+		return func(string) error {
+			cfg.Case = mode
+			return nil
+		}
+	}
+	fs.BoolFunc("smart-case", "case-insensitive unless the query has an uppercase letter (default)", setCase("smart"))
+	fs.BoolFunc("i", "case-insensitive match", setCase("ignore"))
+	fs.BoolFunc("ignore-case", "case-insensitive match", setCase("ignore"))
+	fs.BoolFunc("no-ignore-case", "case-sensitive match (same as +i)", setCase("respect"))
 
 	// Parse
 	if err := fs.Parse(args); err != nil {
@@ -53,11 +144,21 @@ func ParseFlags(args []string) (*Config, error) {
 		cfg.ExactMode = false
 	}
 
+	// --history-rank and --learn rank; --no-sort still wins below
+	if cfg.HistoryRank || cfg.Learn {
+		cfg.Rank = true
+	}
+
 	// --no-sort forces ranking off regardless of --rank
 	if noSort {
 		cfg.Rank = false
 	}
 
+	// --no-extended forces extended syntax off regardless of --extended
+	if noExtended {
+		cfg.Extended = false
+	}
+
 	// Reject unknown markup formats early so callers see a clear error.
 	switch cfg.Markup {
 	case "", "pango":
@@ -65,6 +166,61 @@ func ParseFlags(args []string) (*Config, error) {
 	default:
 		return nil, fmt.Errorf("unsupported --markup value %q (want \"\" or \"pango\")", cfg.Markup)
 	}
+	if cfg.ANSI && cfg.Markup != "" {
+		return nil, fmt.Errorf("--ansi and --markup can't be combined")
+	}
+
+	switch cfg.Algo {
+	case "v1", "v2":
+		// ok
+	default:
+		return nil, fmt.Errorf("unsupported --algo value %q (want \"v1\" or \"v2\")", cfg.Algo)
+	}
+
+	if cfg.Typos < 0 || cfg.Typos > 2 {
+		return nil, fmt.Errorf("unsupported --typos value %d (want 0, 1 or 2)", cfg.Typos)
+	}
+
+	if cfg.HistoryKey == "" {
+		return nil, fmt.Errorf("--history-key must not be empty")
+	}
+
+	var err error
+	if nth != "" {
+		if cfg.Nth, err = input.ParseFieldRanges(nth); err != nil {
+			return nil, fmt.Errorf("invalid --nth value %q: %v", nth, err)
+		}
+	}
+	if withNth != "" {
+		if cfg.WithNth, err = input.ParseFieldRanges(withNth); err != nil {
+			return nil, fmt.Errorf("invalid --with-nth value %q: %v", withNth, err)
+		}
+	}
 
 	return cfg, nil
 }
+
+// ParseOptions returns the stdin parsing options the flags select.
+func (c *Config) ParseOptions() input.ParseOptions {
+	opts := input.ParseOptions{Markup: c.Markup, ANSI: c.ANSI, Nth: c.Nth, WithNth: c.WithNth}
+	if c.Delimiter != "" {
+		opts.Delimiter = input.ParseDelimiter(c.Delimiter)
+	}
+	return opts
+}
+
+// rewritePlusFlags maps fzf's "+x" style negative flags onto long flags the
+// flag package understands. Stops at "--" like flag parsing does.
+func rewritePlusFlags(args []string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+	for i, a := range out {
+		if a == "--" {
+			break
+		}
+		if a == "+i" {
+			out[i] = "--no-ignore-case"
+		}
+	}
+	return out
+}
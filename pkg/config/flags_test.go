@@ -1,7 +1,10 @@
 package config
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
 )
 
 func TestParseFlags_Exact(t *testing.T) {
@@ -143,6 +146,19 @@ func TestParseFlags_MarkupPango(t *testing.T) {
 	}
 }
 
+func TestParseFlags_ANSI(t *testing.T) {
+	cfg, err := ParseFlags([]string{"--ansi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.ANSI || !cfg.ParseOptions().ANSI {
+		t.Errorf("ANSI = %v, ParseOptions().ANSI = %v, want both set", cfg.ANSI, cfg.ParseOptions().ANSI)
+	}
+	if _, err := ParseFlags([]string{"--ansi", "--markup=pango"}); err == nil {
+		t.Error("expected an error combining --ansi and --markup")
+	}
+}
+
 func TestParseFlags_MarkupRejectsUnknown(t *testing.T) {
 	_, err := ParseFlags([]string{"--markup=html"})
 	if err == nil {
@@ -219,3 +235,323 @@ func TestHighlightMatchesWithOtherFlags(t *testing.T) {
 		t.Errorf("Height = %d, want 50", cfg.Height)
 	}
 }
+
+func TestParseFlags_AlgoDefault(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Algo != "v1" {
+		t.Errorf("Algo = %q, want %q by default", cfg.Algo, "v1")
+	}
+}
+
+func TestParseFlags_AlgoV2(t *testing.T) {
+	cfg, err := ParseFlags([]string{"--fuzzy", "--algo=v2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Algo != "v2" {
+		t.Errorf("Algo = %q, want %q", cfg.Algo, "v2")
+	}
+}
+
+func TestParseFlags_AlgoRejectsUnknown(t *testing.T) {
+	_, err := ParseFlags([]string{"--algo=v3"})
+	if err == nil {
+		t.Fatal("expected error for unsupported algo value")
+	}
+}
+
+func TestParseFlags_ExtendedDefault(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Extended {
+		t.Error("expected Extended false by default: existing queries keep their meaning")
+	}
+}
+
+func TestParseFlags_Extended(t *testing.T) {
+	for _, args := range [][]string{{"-x"}, {"--extended"}} {
+		cfg, err := ParseFlags(args)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", args, err)
+		}
+		if !cfg.Extended {
+			t.Errorf("%v: expected Extended true", args)
+		}
+	}
+}
+
+func TestParseFlags_NoExtended(t *testing.T) {
+	cfg, err := ParseFlags([]string{"--extended", "--no-extended"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Extended {
+		t.Error("expected Extended false with --no-extended")
+	}
+}
+
+func TestParseFlags_CaseDefaultSmart(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Case != "smart" {
+		t.Errorf("Case = %q, want %q by default", cfg.Case, "smart")
+	}
+}
+
+func TestParseFlags_CaseFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-i"}, "ignore"},
+		{[]string{"--ignore-case"}, "ignore"},
+		{[]string{"+i"}, "respect"},
+		{[]string{"--no-ignore-case"}, "respect"},
+		{[]string{"-i", "+i"}, "respect"},
+		{[]string{"+i", "--smart-case"}, "smart"},
+		{[]string{"+i", "--rank"}, "respect"},
+	}
+	for _, tt := range tests {
+		cfg, err := ParseFlags(tt.args)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if cfg.Case != tt.want {
+			t.Errorf("%v: Case = %q, want %q", tt.args, cfg.Case, tt.want)
+		}
+	}
+}
+
+// +i must not stop flag parsing the way a positional argument would.
+func TestParseFlags_PlusIKeepsParsing(t *testing.T) {
+	cfg, err := ParseFlags([]string{"+i", "--rank"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Rank {
+		t.Error("expected --rank after +i to be parsed")
+	}
+}
+
+func TestParseFlags_Literal(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Literal {
+		t.Error("expected Literal false by default (normalization on)")
+	}
+
+	cfg, err = ParseFlags([]string{"--literal"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Literal {
+		t.Error("expected Literal true with --literal")
+	}
+}
+
+func TestParseFlags_Regex(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Regex {
+		t.Error("expected Regex false by default")
+	}
+
+	cfg, err = ParseFlags([]string{"--regex", "--fuzzy"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Regex {
+		t.Error("expected Regex true with --regex")
+	}
+}
+
+func TestParseFlags_Matcher(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Matcher != "" {
+		t.Errorf("expected empty Matcher by default, got %q", cfg.Matcher)
+	}
+
+	cfg, err = ParseFlags([]string{"--matcher=regex"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Matcher != "regex" {
+		t.Errorf("expected Matcher %q, got %q", "regex", cfg.Matcher)
+	}
+}
+
+func TestParseFlags_Fields(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Delimiter != "" || cfg.Nth != nil || cfg.WithNth != nil {
+		t.Errorf("expected no field options by default, got %q %v %v", cfg.Delimiter, cfg.Nth, cfg.WithNth)
+	}
+	if opts := cfg.ParseOptions(); opts.Delimiter != nil {
+		t.Error("expected the AWK-style delimiter by default")
+	}
+
+	cfg, err = ParseFlags([]string{"--delimiter", `\t`, "--nth=1", "--with-nth", "1,3.."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Delimiter != `\t` {
+		t.Errorf("expected Delimiter %q, got %q", `\t`, cfg.Delimiter)
+	}
+	if !reflect.DeepEqual(cfg.Nth, []input.FieldRange{{From: 1, To: 1}}) {
+		t.Errorf("unexpected Nth %v", cfg.Nth)
+	}
+	if !reflect.DeepEqual(cfg.WithNth, []input.FieldRange{{From: 1, To: 1}, {From: 3}}) {
+		t.Errorf("unexpected WithNth %v", cfg.WithNth)
+	}
+
+	item := input.ParseLineWithOptions("a.go\t12\tmon", 0, cfg.ParseOptions())
+	if item.Text != "a.go\tmon" || item.Raw != "a.go\t12\tmon" {
+		t.Errorf("unexpected item %q (raw %q)", item.Text, item.Raw)
+	}
+
+	for _, args := range [][]string{{"--nth=0"}, {"-n", "x"}, {"--with-nth=1,"}} {
+		if _, err := ParseFlags(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestParseFlags_Typos(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Typos != 0 {
+		t.Errorf("expected Typos 0 by default, got %d", cfg.Typos)
+	}
+
+	cfg, err = ParseFlags([]string{"--typos=2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Typos != 2 {
+		t.Errorf("expected Typos 2, got %d", cfg.Typos)
+	}
+
+	if _, err := ParseFlags([]string{"--typos=3"}); err == nil {
+		t.Error("expected an error for --typos=3")
+	}
+}
+
+func TestParseFlags_Scheme(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Scheme != "default" {
+		t.Errorf("expected Scheme \"default\", got %q", cfg.Scheme)
+	}
+
+	cfg, err = ParseFlags([]string{"--rank", "--scheme=path"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Scheme != "path" {
+		t.Errorf("expected Scheme \"path\", got %q", cfg.Scheme)
+	}
+}
+
+func TestParseFlags_HistoryRank(t *testing.T) {
+	cfg, err := ParseFlags([]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.HistoryRank || cfg.HistoryKey != "default" {
+		t.Errorf("expected history off with key \"default\", got %v %q", cfg.HistoryRank, cfg.HistoryKey)
+	}
+
+	cfg, err = ParseFlags([]string{"--history-rank", "--history-key=files"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.HistoryRank || !cfg.Rank {
+		t.Errorf("expected --history-rank to enable ranking, got HistoryRank=%v Rank=%v", cfg.HistoryRank, cfg.Rank)
+	}
+	if cfg.HistoryKey != "files" {
+		t.Errorf("expected HistoryKey \"files\", got %q", cfg.HistoryKey)
+	}
+
+	if _, err := ParseFlags([]string{"--history-key="}); err == nil {
+		t.Error("expected an error for an empty --history-key")
+	}
+}
+
+func TestParseFlags_Learn(t *testing.T) {
+	cfg, err := ParseFlags([]string{"--learn", "--history-key=apps"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Learn || !cfg.Rank || cfg.HistoryKey != "apps" {
+		t.Errorf("expected --learn to rank in context \"apps\", got Learn=%v Rank=%v key %q", cfg.Learn, cfg.Rank, cfg.HistoryKey)
+	}
+
+	cfg, err = ParseFlags([]string{"--forget", "ter"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Forget != "ter" {
+		t.Errorf("expected Forget \"ter\", got %q", cfg.Forget)
+	}
+}
+
+func TestParseFlags_RankerOverrides(t *testing.T) {
+	cfg, err := ParseFlags([]string{"--rank", "--tiebreak=length,index", "--weights=early=40", "--ranker-config=/etc/goose/files.conf"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Tiebreak != "length,index" || cfg.Weights != "early=40" || cfg.RankerConfig != "/etc/goose/files.conf" {
+		t.Errorf("unexpected overrides: tiebreak %q, weights %q, config %q", cfg.Tiebreak, cfg.Weights, cfg.RankerConfig)
+	}
+}
+
+func TestParseFlags_Explain(t *testing.T) {
+	cfg, err := ParseFlags([]string{"--rank", "--explain"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Explain || !cfg.Rank {
+		t.Errorf("Explain = %v, Rank = %v, want both on", cfg.Explain, cfg.Rank)
+	}
+	if cfg, _ := ParseFlags(nil); cfg.Explain {
+		t.Error("Explain should default to off")
+	}
+}
+
+func TestParseFlags_NulDelimited(t *testing.T) {
+	cfg, err := ParseFlags([]string{"--read0", "--print0", "--multi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Read0 || !cfg.Print0 {
+		t.Errorf("Read0 = %v, Print0 = %v, want both on", cfg.Read0, cfg.Print0)
+	}
+
+	if read0, print0 := ClientOptions([]string{"--rank", "--read0"}); !read0 || print0 {
+		t.Errorf("ClientOptions = %v, %v, want read0 only", read0, print0)
+	}
+	// The daemon reports bad flags; the client just reads lines.
+	if read0, print0 := ClientOptions([]string{"--read0", "--bind=x"}); read0 || print0 {
+		t.Errorf("ClientOptions with a bad flag = %v, %v, want neither", read0, print0)
+	}
+}
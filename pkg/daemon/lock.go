@@ -62,9 +62,16 @@ func (l *LockFile) Release() error {
 
 // DefaultLockPath is the canonical pidfile location.
 func DefaultLockPath() string {
+	return CachePath("goose-launcher.pid")
+}
+
+// CachePath is where the daemon keeps the file name: in the user's cache
+// directory, or the temp directory when there is none (not ideal — users
+// may collide — but better than failing to start).
+func CachePath(name string) string {
 	cache, err := os.UserCacheDir()
 	if err != nil {
-		return filepath.Join(os.TempDir(), "goose-launcher.pid")
+		return filepath.Join(os.TempDir(), name)
 	}
-	return filepath.Join(cache, "goose-launcher.pid")
+	return filepath.Join(cache, name)
 }
@@ -44,3 +44,16 @@ func TestAcquireLock_ReleaseLetsAnotherIn(t *testing.T) {
 	}
 	second.Release()
 }
+
+// The pidfile, the socket and the stores the daemon opens all share one
+// directory.
+func TestCachePath_SharedDirectory(t *testing.T) {
+	t.Setenv("GOOSE_LAUNCHER_SOCKET", "")
+	dir := filepath.Dir(DefaultLockPath())
+	if got := filepath.Dir(DefaultSocketPath()); got != dir {
+		t.Errorf("socket in %s, pidfile in %s", got, dir)
+	}
+	if got := CachePath("goose-launcher-history.json"); got != filepath.Join(dir, "goose-launcher-history.json") {
+		t.Errorf("CachePath = %s, want it in %s", got, dir)
+	}
+}
@@ -8,7 +8,10 @@
 //	[1-byte tag][4-byte big-endian length N][N payload bytes]
 //
 // Payload is JSON-encoded for tagged message bodies (Hello, StdinChunk,
-// Response). MsgStdinEOF carries no payload (length=0).
+// Response). MsgStdinEOF carries no payload (length=0). encoding/json
+// would replace invalid UTF-8 with U+FFFD, so stdin lines and the
+// selection that aren't valid UTF-8 (e.g. Latin-1 file names) travel
+// base64-encoded alongside, and arrive byte for byte.
 //
 // Conversation shape (one connection per client invocation):
 //
@@ -31,19 +34,20 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"slices"
+	"unicode/utf8"
 )
 
 // ProtocolVersion is bumped on every wire-format change. Mismatched versions
 // are a hard error — the daemon does not attempt backward compatibility.
-const ProtocolVersion = 2
+const ProtocolVersion = 3
 
 // MaxFrameSize caps a single frame at 256 MiB to prevent a malicious or
 // buggy peer from forcing the other side into an OOM. The launcher's actual
 // usage is many orders of magnitude below this.
 const MaxFrameSize = 256 * 1024 * 1024
 
-// Message tag values. Stable across protocol versions within v2.
+// Message tag values. Stable across protocol versions since v2.
 const (
 	MsgTagHello      uint8 = 1
 	MsgTagStdinChunk uint8 = 2
@@ -61,20 +65,94 @@ type Hello struct {
 
 // StdinChunk carries a batch of stdin lines. The client batches lines (by
 // count, byte size, or short idle interval) before sending; the daemon
-// appends them to the live launcher items as each chunk arrives.
+// appends them to the live launcher items as each chunk arrives. With
+// --read0 each "line" is a NUL-terminated item and may contain newlines.
+// Lines need not be valid UTF-8.
 type StdinChunk struct {
 	Lines []string `json:"lines"`
 }
 
+// stdinChunkWire is StdinChunk as encoded: the lines that aren't valid
+// UTF-8 are empty in Lines and kept, by index, in Bytes.
+type stdinChunkWire struct {
+	Lines []string       `json:"lines"`
+	Bytes map[int][]byte `json:"bytes,omitempty"`
+}
+
+// MarshalJSON encodes c byte for byte (see stdinChunkWire).
+func (c StdinChunk) MarshalJSON() ([]byte, error) {
+	wire := stdinChunkWire{Lines: c.Lines}
+	for i, line := range c.Lines {
+		if utf8.ValidString(line) {
+			continue
+		}
+		if wire.Bytes == nil {
+			wire.Bytes = make(map[int][]byte)
+			wire.Lines = slices.Clone(c.Lines)
+		}
+		wire.Bytes[i] = []byte(line)
+		wire.Lines[i] = ""
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes what MarshalJSON encoded.
+func (c *StdinChunk) UnmarshalJSON(b []byte) error {
+	var wire stdinChunkWire
+	if err := json.Unmarshal(b, &wire); err != nil {
+		return err
+	}
+	for i, line := range wire.Bytes {
+		if i < 0 || i >= len(wire.Lines) {
+			return fmt.Errorf("line %d of %d out of range", i, len(wire.Lines))
+		}
+		wire.Lines[i] = string(line)
+	}
+	c.Lines = wire.Lines
+	return nil
+}
+
 // Response carries the user's selection and the exit code the client should
 // propagate. Error is set when the daemon couldn't process the request at
 // all (parse error, internal panic, etc.); the client prints it to stderr.
+// Selection need not be valid UTF-8: it's made of stdin lines.
 type Response struct {
 	Selection string `json:"selection"`
 	ExitCode  int    `json:"exit_code"`
 	Error     string `json:"error,omitempty"`
 }
 
+// responseWire is Response as encoded: a Selection that isn't valid UTF-8
+// travels in SelectionBytes instead.
+type responseWire struct {
+	Selection      string `json:"selection"`
+	SelectionBytes []byte `json:"selection_bytes,omitempty"`
+	ExitCode       int    `json:"exit_code"`
+	Error          string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes r with its Selection byte for byte.
+func (r Response) MarshalJSON() ([]byte, error) {
+	wire := responseWire{Selection: r.Selection, ExitCode: r.ExitCode, Error: r.Error}
+	if !utf8.ValidString(r.Selection) {
+		wire.Selection, wire.SelectionBytes = "", []byte(r.Selection)
+	}
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes what MarshalJSON encoded.
+func (r *Response) UnmarshalJSON(b []byte) error {
+	var wire responseWire
+	if err := json.Unmarshal(b, &wire); err != nil {
+		return err
+	}
+	*r = Response{Selection: wire.Selection, ExitCode: wire.ExitCode, Error: wire.Error}
+	if wire.SelectionBytes != nil {
+		r.Selection = string(wire.SelectionBytes)
+	}
+	return nil
+}
+
 // WriteMsg writes a tagged, length-prefixed frame.
 func WriteMsg(w io.Writer, tag uint8, payload []byte) error {
 	if len(payload) > MaxFrameSize {
@@ -196,11 +274,5 @@ func DefaultSocketPath() string {
 	if v := os.Getenv("GOOSE_LAUNCHER_SOCKET"); v != "" {
 		return v
 	}
-	cache, err := os.UserCacheDir()
-	if err != nil {
-		// Fallback: tmp. Not ideal (multi-user collision risk) but better
-		// than failing to start.
-		return filepath.Join(os.TempDir(), "goose-launcher.sock")
-	}
-	return filepath.Join(cache, "goose-launcher.sock")
+	return CachePath("goose-launcher.sock")
 }
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"bytes"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -41,6 +42,7 @@ func TestRoundTripStdinChunk(t *testing.T) {
 			"line two",
 			"line three with \"quotes\" and \x00 nulls",
 			"",
+			"a --read0 item\nspanning lines\r\n",
 		},
 	}
 	var buf bytes.Buffer
@@ -68,6 +70,54 @@ func TestRoundTripStdinChunk(t *testing.T) {
 	}
 }
 
+// encoding/json would turn invalid UTF-8 into U+FFFD; chunks and
+// responses must carry such lines byte for byte.
+func TestRoundTripInvalidUTF8(t *testing.T) {
+	in := &StdinChunk{Lines: []string{
+		"valid",
+		"caf\xe9.txt", // Latin-1
+		"\xff\xfe",
+		"",
+		"trailing \xc3", // truncated sequence
+	}}
+	var buf bytes.Buffer
+	if err := WriteChunk(&buf, in); err != nil {
+		t.Fatalf("WriteChunk: %v", err)
+	}
+	_, payload, err := ReadMsg(&buf)
+	if err != nil {
+		t.Fatalf("ReadMsg: %v", err)
+	}
+	out, err := DecodeChunk(payload)
+	if err != nil {
+		t.Fatalf("DecodeChunk: %v", err)
+	}
+	if !reflect.DeepEqual(in.Lines, out.Lines) {
+		t.Errorf("lines %q, want %q", out.Lines, in.Lines)
+	}
+	if in.Lines[1] != "caf\xe9.txt" {
+		t.Error("WriteChunk modified its input")
+	}
+
+	resp := &Response{Selection: "caf\xe9.txt\nvalid", ExitCode: 0}
+	if err := WriteResponse(&buf, resp); err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+	got, err := ReadResponse(&buf)
+	if err != nil {
+		t.Fatalf("ReadResponse: %v", err)
+	}
+	if *got != *resp {
+		t.Errorf("response %+v, want %+v", got, resp)
+	}
+}
+
+func TestDecodeChunkRejectsBadIndex(t *testing.T) {
+	if _, err := DecodeChunk([]byte(`{"lines":["a"],"bytes":{"1":"/w=="}}`)); err == nil {
+		t.Error("DecodeChunk should reject bytes for a line past the end")
+	}
+}
+
 func TestRoundTripStdinEOF(t *testing.T) {
 	var buf bytes.Buffer
 	if err := WriteEOF(&buf); err != nil {
@@ -0,0 +1,248 @@
+// Package history remembers what the user accepted, so the ranker can
+// float frequently and recently chosen items to the top (--history-rank).
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+const (
+	// MaxEntries caps the items remembered per key. Recording past it
+	// forgets the entries with the lowest frecency.
+	MaxEntries = 1000
+	// MaxAge forgets items not chosen for this long.
+	MaxAge = 90 * 24 * time.Hour
+)
+
+// Entry is one remembered item of a key. Raw need not be valid UTF-8: it's
+// a stdin line.
+type Entry struct {
+	Raw    string    `json:"raw"`
+	Plugin string    `json:"plugin,omitempty"`
+	Count  int       `json:"count"` // Times accepted
+	Last   time.Time `json:"last"`  // Most recent acceptance
+}
+
+// entryWire is Entry as stored: a Raw that isn't valid UTF-8, which JSON
+// strings can't hold, is kept in RawBytes instead (as base64).
+type entryWire struct {
+	entryFields
+	RawBytes []byte `json:"raw_bytes,omitempty"`
+}
+
+// entryFields is Entry without its JSON methods.
+type entryFields Entry
+
+// MarshalJSON encodes e with its Raw byte for byte.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	wire := entryWire{entryFields: entryFields(e)}
+	wire.Raw, wire.RawBytes = splitRaw(e.Raw)
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes what MarshalJSON encoded.
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var wire entryWire
+	if err := json.Unmarshal(b, &wire); err != nil {
+		return err
+	}
+	*e = Entry(wire.entryFields)
+	e.Raw = joinRaw(wire.Raw, wire.RawBytes)
+	return nil
+}
+
+// splitRaw returns raw as stored: itself if it's valid UTF-8, otherwise
+// "" and its bytes.
+func splitRaw(raw string) (string, []byte) {
+	if utf8.ValidString(raw) {
+		return raw, nil
+	}
+	return "", []byte(raw)
+}
+
+// joinRaw undoes splitRaw.
+func joinRaw(raw string, rawBytes []byte) string {
+	if rawBytes != nil {
+		return string(rawBytes)
+	}
+	return raw
+}
+
+// Frecency is the entry's count weighted by how recently it was last
+// chosen, in the buckets zoxide and Firefox use: a pick from the last hour
+// counts four times, from the last day twice, from the last week once and
+// anything older a quarter.
+func (e *Entry) Frecency(now time.Time) float64 {
+	age := now.Sub(e.Last)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 1
+	}
+	return float64(e.Count) * weight
+}
+
+// Store is the selection history on disk: a JSON file of entries per key
+// (--history-key), so each plugin's list learns on its own. It isn't safe
+// for concurrent use; the daemon serves one request at a time.
+type Store struct {
+	path string
+	keys map[string][]Entry
+}
+
+// FileName is the history's file name; the daemon keeps it beside its
+// pidfile (see daemon.CachePath).
+const FileName = "goose-launcher-history.json"
+
+// Open loads the store at path. A missing file is an empty store; it's
+// created on the first Save.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, keys: map[string][]Entry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.keys); err != nil {
+		return nil, fmt.Errorf("parse history %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Record counts one acceptance of each item under key at now, then ages
+// out and caps the key's entries.
+func (s *Store) Record(key string, items []input.Item, now time.Time) {
+	entries := s.keys[key]
+	for _, it := range items {
+		i := indexOf(entries, it.Raw)
+		if i < 0 {
+			entries = append(entries, Entry{Raw: it.Raw})
+			i = len(entries) - 1
+		}
+		entries[i].Plugin = it.Plugin
+		entries[i].Count++
+		entries[i].Last = now
+	}
+	s.keys[key] = prune(entries, now)
+}
+
+// Entries returns key's entries, most frecent first.
+func (s *Store) Entries(key string, now time.Time) []Entry {
+	entries := append([]Entry(nil), s.keys[key]...)
+	sortByFrecency(entries, now)
+	return entries
+}
+
+// Save writes the store back to its path, atomically.
+func (s *Store) Save() error {
+	data, err := json.Marshal(s.keys)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic replaces path with data through a temp file and a
+// rename, so a crash mid-write never leaves a truncated file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(path), err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Scores is a snapshot of one key's frecencies, normalized for the ranker
+// (it implements ranker.History).
+type Scores map[string]float64
+
+// Scores snapshots key's frecencies at now. They're scaled by log so an
+// item chosen once still gets a visible lift next to the favourite, which
+// scores 1.
+func (s *Store) Scores(key string, now time.Time) Scores {
+	entries := s.keys[key]
+	if len(entries) == 0 {
+		return nil
+	}
+	top := 0.0
+	for i := range entries {
+		top = max(top, entries[i].Frecency(now))
+	}
+	scores := make(Scores, len(entries))
+	for i := range entries {
+		scores[entries[i].Raw] = math.Log1p(entries[i].Frecency(now)) / math.Log1p(top)
+	}
+	return scores
+}
+
+// Frecency reports item's normalized frecency, 0 for items never chosen.
+func (sc Scores) Frecency(item input.Item) float64 {
+	return sc[item.Raw]
+}
+
+func indexOf(entries []Entry, raw string) int {
+	for i := range entries {
+		if entries[i].Raw == raw {
+			return i
+		}
+	}
+	return -1
+}
+
+// prune drops entries older than MaxAge, then the least frecent past
+// MaxEntries.
+func prune(entries []Entry, now time.Time) []Entry {
+	kept := entries[:0]
+	for _, e := range entries {
+		if now.Sub(e.Last) < MaxAge {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) > MaxEntries {
+		sortByFrecency(kept, now)
+		kept = kept[:MaxEntries]
+	}
+	return kept
+}
+
+// sortByFrecency orders entries most frecent first, most recent on ties.
+func sortByFrecency(entries []Entry, now time.Time) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		fi, fj := entries[i].Frecency(now), entries[j].Frecency(now)
+		if fi != fj {
+			return fi > fj
+		}
+		return entries[i].Last.After(entries[j].Last)
+	})
+}
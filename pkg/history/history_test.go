@@ -0,0 +1,122 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func item(raw string) input.Item {
+	return input.Item{Raw: raw, Plugin: "files"}
+}
+
+func TestStore_SaveAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "history.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open missing file: %v", err)
+	}
+	s.Record("files", []input.Item{item("a.go")}, now)
+	s.Record("files", []input.Item{item("a.go"), item("b.go")}, now)
+	s.Record("apps", []input.Item{item("term")}, now)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got := s.Entries("files", now)
+	if len(got) != 2 || got[0].Raw != "a.go" || got[0].Count != 2 || got[0].Plugin != "files" {
+		t.Errorf("Entries(files) = %+v, want a.go twice then b.go", got)
+	}
+	if !got[0].Last.Equal(now) {
+		t.Errorf("Last = %v, want %v", got[0].Last, now)
+	}
+	if got := s.Entries("apps", now); len(got) != 1 || got[0].Raw != "term" {
+		t.Errorf("Entries(apps) = %+v, want only term: keys are separate", got)
+	}
+}
+
+func TestEntry_FrecencyFavorsRecent(t *testing.T) {
+	recent := Entry{Count: 2, Last: now.Add(-time.Minute)}
+	old := Entry{Count: 5, Last: now.Add(-30 * 24 * time.Hour)}
+	if recent.Frecency(now) <= old.Frecency(now) {
+		t.Errorf("two picks a minute ago (%v) should beat five a month ago (%v)",
+			recent.Frecency(now), old.Frecency(now))
+	}
+}
+
+func TestStore_AgesOutAndCaps(t *testing.T) {
+	s, _ := Open(filepath.Join(t.TempDir(), "history.json"))
+	s.Record("k", []input.Item{item("stale")}, now.Add(-MaxAge-time.Hour))
+	s.Record("k", []input.Item{item("fresh")}, now)
+	if got := s.Entries("k", now); len(got) != 1 || got[0].Raw != "fresh" {
+		t.Errorf("Entries = %+v, want the stale entry aged out", got)
+	}
+
+	s.Record("k", []input.Item{item("fresh")}, now)
+	for i := 0; i < MaxEntries; i++ {
+		s.Record("k", []input.Item{item(fmt.Sprintf("once-%d", i))}, now)
+	}
+	got := s.Entries("k", now)
+	if len(got) != MaxEntries {
+		t.Fatalf("len(Entries) = %d, want the cap %d", len(got), MaxEntries)
+	}
+	if got[0].Raw != "fresh" {
+		t.Errorf("most frecent = %q, want fresh to survive the cap", got[0].Raw)
+	}
+}
+
+func TestStore_Scores(t *testing.T) {
+	s, _ := Open(filepath.Join(t.TempDir(), "history.json"))
+	if sc := s.Scores("k", now); sc.Frecency(item("a")) != 0 {
+		t.Errorf("empty history scored %v", sc.Frecency(item("a")))
+	}
+	for i := 0; i < 5; i++ {
+		s.Record("k", []input.Item{item("fav")}, now)
+	}
+	s.Record("k", []input.Item{item("once")}, now)
+
+	sc := s.Scores("k", now)
+	if got := sc.Frecency(item("fav")); got != 1 {
+		t.Errorf("favourite scored %v, want 1", got)
+	}
+	if got := sc.Frecency(item("once")); got <= 0 || got >= 1 {
+		t.Errorf("single pick scored %v, want between 0 and 1", got)
+	}
+	if got := sc.Frecency(item("never")); got != 0 {
+		t.Errorf("unknown item scored %v, want 0", got)
+	}
+}
+
+// A Latin-1 file name survives a save byte for byte, so it's still found
+// by its Raw line after a reload.
+func TestStore_InvalidUTF8RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	raw := "caf\xe9.txt"
+	s, _ := Open(path)
+	s.Record("files", []input.Item{item(raw), item("plain.txt")}, now)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	s.Record("files", []input.Item{item(raw)}, now)
+	got := s.Entries("files", now)
+	if len(got) != 2 || got[0].Raw != raw || got[0].Count != 2 || got[1].Raw != "plain.txt" {
+		t.Errorf("Entries = %+v, want %q counted twice, then plain.txt", got, raw)
+	}
+	if s.Scores("files", now).Frecency(item(raw)) != 1 {
+		t.Error("the reloaded entry should score for the same line")
+	}
+}
@@ -0,0 +1,253 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+const (
+	// MaxLearnedQueries caps the queries remembered per context; learning
+	// past it forgets the least recently used.
+	MaxLearnedQueries = 5000
+	// maxLearnedPrefix is the longest query prefix learned, in runes.
+	// Longer queries are specific enough to find their item unaided.
+	maxLearnedPrefix = 32
+	// maxPicks caps the items remembered per query.
+	maxPicks = 8
+	// pickDecay scales the earlier picks of a query each time another is
+	// learned, so a new habit takes over after a few acceptances.
+	pickDecay = 0.8
+	// minPickWeight is the weight below which a decayed pick is forgotten.
+	minPickWeight = 0.1
+)
+
+// Pick is an item chosen for a query and how strongly: each acceptance
+// adds one after decaying the query's other picks.
+type Pick struct {
+	Raw    string  `json:"raw"`
+	Weight float64 `json:"weight"`
+}
+
+// pickWire is Pick as stored, with Raw kept like Entry's (see entryWire).
+type pickWire struct {
+	pickFields
+	RawBytes []byte `json:"raw_bytes,omitempty"`
+}
+
+// pickFields is Pick without its JSON methods.
+type pickFields Pick
+
+// MarshalJSON encodes p with its Raw byte for byte.
+func (p Pick) MarshalJSON() ([]byte, error) {
+	wire := pickWire{pickFields: pickFields(p)}
+	wire.Raw, wire.RawBytes = splitRaw(p.Raw)
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON decodes what MarshalJSON encoded.
+func (p *Pick) UnmarshalJSON(b []byte) error {
+	var wire pickWire
+	if err := json.Unmarshal(b, &wire); err != nil {
+		return err
+	}
+	*p = Pick(wire.pickFields)
+	p.Raw = joinRaw(wire.Raw, wire.RawBytes)
+	return nil
+}
+
+// Association is what a context learned for one normalized query.
+type Association struct {
+	Picks []Pick    `json:"picks"` // Strongest first
+	Last  time.Time `json:"last"`  // Most recent acceptance, for eviction
+}
+
+// Learned remembers which items the user accepts for each query, per
+// context (--history-key): Alfred's knowledge. Accepting an item after
+// typing "term" teaches "t", "te", "ter" and "term", so the next time any
+// of them is typed the usual pick ranks first. Like Store it isn't safe for
+// concurrent use.
+type Learned struct {
+	path     string
+	contexts map[string]map[string]*Association
+}
+
+// LearnedFileName is the learned queries' file name, kept beside the
+// selection history.
+const LearnedFileName = "goose-launcher-learned.json"
+
+// OpenLearned loads the learned queries at path. A missing file is empty.
+func OpenLearned(path string) (*Learned, error) {
+	l := &Learned{path: path, contexts: map[string]map[string]*Association{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read learned queries: %w", err)
+	}
+	if err := json.Unmarshal(data, &l.contexts); err != nil {
+		return nil, fmt.Errorf("parse learned queries %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// NormalizeQuery is the form queries are learned and looked up in:
+// lowercased, with runs of whitespace collapsed and the ends trimmed.
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// Learn records that items were accepted for query in context, under the
+// query and each of its prefixes.
+func (l *Learned) Learn(context, query string, items []input.Item, now time.Time) {
+	q := []rune(NormalizeQuery(query))
+	if len(q) == 0 || len(items) == 0 {
+		return
+	}
+	queries := l.contexts[context]
+	if queries == nil {
+		queries = map[string]*Association{}
+		l.contexts[context] = queries
+	}
+	for n := 1; n <= min(len(q), maxLearnedPrefix); n++ {
+		prefix := string(q[:n])
+		if q[n-1] == ' ' {
+			continue // "foo " looks up as "foo"
+		}
+		a := queries[prefix]
+		if a == nil {
+			a = &Association{}
+			queries[prefix] = a
+		}
+		a.learn(items)
+		a.Last = now
+	}
+	evictQueries(queries)
+}
+
+func (a *Association) learn(items []input.Item) {
+	for i := range a.Picks {
+		a.Picks[i].Weight *= pickDecay
+	}
+	for _, it := range items {
+		found := false
+		for i := range a.Picks {
+			if a.Picks[i].Raw == it.Raw {
+				a.Picks[i].Weight++
+				found = true
+				break
+			}
+		}
+		if !found {
+			a.Picks = append(a.Picks, Pick{Raw: it.Raw, Weight: 1})
+		}
+	}
+	sort.SliceStable(a.Picks, func(i, j int) bool {
+		return a.Picks[i].Weight > a.Picks[j].Weight
+	})
+	kept := a.Picks[:0]
+	for _, p := range a.Picks {
+		if p.Weight >= minPickWeight && len(kept) < maxPicks {
+			kept = append(kept, p)
+		}
+	}
+	a.Picks = kept
+}
+
+// evictQueries drops the least recently used queries past
+// MaxLearnedQueries.
+func evictQueries(queries map[string]*Association) {
+	if len(queries) <= MaxLearnedQueries {
+		return
+	}
+	keys := make([]string, 0, len(queries))
+	for q := range queries {
+		keys = append(keys, q)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return queries[keys[i]].Last.After(queries[keys[j]].Last)
+	})
+	for _, q := range keys[MaxLearnedQueries:] {
+		delete(queries, q)
+	}
+}
+
+// Forget drops what context learned for query and for every longer query
+// it begins, so typing on doesn't bring the pick back, and reports how
+// many queries were forgotten. Shorter prefixes keep their picks.
+func (l *Learned) Forget(context, query string) int {
+	q := NormalizeQuery(query)
+	queries := l.contexts[context]
+	if q == "" || queries == nil {
+		return 0
+	}
+	n := 0
+	for learned := range queries {
+		if strings.HasPrefix(learned, q) {
+			delete(queries, learned)
+			n++
+		}
+	}
+	if len(queries) == 0 {
+		delete(l.contexts, context)
+	}
+	return n
+}
+
+// Picks returns the items learned for query in context, strongest first.
+func (l *Learned) Picks(context, query string) []Pick {
+	a := l.contexts[context][NormalizeQuery(query)]
+	if a == nil {
+		return nil
+	}
+	return append([]Pick(nil), a.Picks...)
+}
+
+// Save writes the learned queries back to their path, atomically.
+func (l *Learned) Save() error {
+	data, err := json.Marshal(l.contexts)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(l.path, data)
+}
+
+// Knowledge is a snapshot of one context's learned queries, with each
+// query's pick weights scaled so its strongest pick scores 1. It
+// implements ranker.Learned.
+type Knowledge map[string]map[string]float64
+
+// Knowledge snapshots what context has learned.
+func (l *Learned) Knowledge(context string) Knowledge {
+	queries := l.contexts[context]
+	if len(queries) == 0 {
+		return nil
+	}
+	k := make(Knowledge, len(queries))
+	for q, a := range queries {
+		if len(a.Picks) == 0 {
+			continue
+		}
+		top := a.Picks[0].Weight
+		picks := make(map[string]float64, len(a.Picks))
+		for _, p := range a.Picks {
+			picks[p.Raw] = p.Weight / top
+		}
+		k[q] = picks
+	}
+	return k
+}
+
+// Picks returns the boost of each item (by Raw) learned for query; nil
+// when nothing was.
+func (k Knowledge) Picks(query string) map[string]float64 {
+	return k[NormalizeQuery(query)]
+}
@@ -0,0 +1,108 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+func TestLearned_LearnsPrefixesPerContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "learned.json")
+	l, err := OpenLearned(path)
+	if err != nil {
+		t.Fatalf("OpenLearned missing file: %v", err)
+	}
+	l.Learn("apps", "  Term ", []input.Item{item("terminal")}, now)
+	if err := l.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	l, err = OpenLearned(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	for _, q := range []string{"t", "te", "TER", "term"} {
+		if picks := l.Picks("apps", q); len(picks) != 1 || picks[0].Raw != "terminal" {
+			t.Errorf("Picks(apps, %q) = %+v, want terminal", q, picks)
+		}
+	}
+	if picks := l.Picks("apps", "terms"); picks != nil {
+		t.Errorf("Picks(apps, terms) = %+v, want nothing learned", picks)
+	}
+	if picks := l.Picks("files", "ter"); picks != nil {
+		t.Errorf("Picks(files, ter) = %+v, want contexts kept apart", picks)
+	}
+}
+
+func TestLearned_NewHabitTakesOver(t *testing.T) {
+	l, _ := OpenLearned(filepath.Join(t.TempDir(), "learned.json"))
+	for i := 0; i < 3; i++ {
+		l.Learn("apps", "ter", []input.Item{item("terminal")}, now)
+	}
+	l.Learn("apps", "ter", []input.Item{item("iterm")}, now)
+	if k := l.Knowledge("apps").Picks("ter"); k["terminal"] != 1 || k["iterm"] >= 1 {
+		t.Errorf("after one switch, Knowledge = %v, want terminal still on top", k)
+	}
+	for i := 0; i < 3; i++ {
+		l.Learn("apps", "ter", []input.Item{item("iterm")}, now)
+	}
+	if picks := l.Picks("apps", "ter"); picks[0].Raw != "iterm" {
+		t.Errorf("Picks = %+v, want iterm to take over", picks)
+	}
+}
+
+func TestLearned_Forget(t *testing.T) {
+	l, _ := OpenLearned(filepath.Join(t.TempDir(), "learned.json"))
+	l.Learn("apps", "term", []input.Item{item("terminal")}, now)
+	l.Learn("apps", "tex", []input.Item{item("texstudio")}, now)
+
+	if n := l.Forget("apps", "ter"); n != 2 {
+		t.Errorf("Forget(ter) = %d, want ter and term forgotten", n)
+	}
+	if picks := l.Picks("apps", "term"); picks != nil {
+		t.Errorf("term still learned: %+v", picks)
+	}
+	if picks := l.Picks("apps", "te"); len(picks) != 2 {
+		t.Errorf("Picks(te) = %+v, want shorter prefixes kept", picks)
+	}
+	if n := l.Forget("files", "ter"); n != 0 {
+		t.Errorf("Forget in an unknown context = %d, want 0", n)
+	}
+}
+
+func TestLearned_EvictsLeastRecentQueries(t *testing.T) {
+	l, _ := OpenLearned(filepath.Join(t.TempDir(), "learned.json"))
+	l.Learn("apps", "old", []input.Item{item("old")}, now.Add(-time.Hour))
+	queries := l.contexts["apps"]
+	for i := 0; len(queries) <= MaxLearnedQueries; i++ {
+		queries[string(rune('A'+i%26))+string(rune(0x4e00+i))] = &Association{Last: now}
+	}
+	l.Learn("apps", "new", []input.Item{item("new")}, now)
+	if len(queries) != MaxLearnedQueries {
+		t.Errorf("%d queries kept, want the cap %d", len(queries), MaxLearnedQueries)
+	}
+	if l.Picks("apps", "old") != nil {
+		t.Error("the least recently used query should be evicted first")
+	}
+}
+
+func TestLearned_InvalidUTF8RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "learned.json")
+	raw := "caf\xe9.txt"
+	l, _ := OpenLearned(path)
+	l.Learn("files", "caf", []input.Item{item(raw)}, now)
+	if err := l.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	l, err := OpenLearned(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	l.Learn("files", "caf", []input.Item{item(raw)}, now)
+	if picks := l.Picks("files", "caf"); len(picks) != 1 || picks[0].Raw != raw {
+		t.Errorf("Picks = %+v, want %q learned once, byte for byte", picks, raw)
+	}
+}
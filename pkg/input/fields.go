@@ -0,0 +1,255 @@
+package input
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sam33r/goose-launcher/pkg/markup"
+)
+
+// FieldRange is one fzf field index expression from --nth / --with-nth:
+// fields From through To, 1-based and inclusive. Negative indices count
+// from the last field (-1 is the last); 0 leaves that end open.
+type FieldRange struct {
+	From, To int
+}
+
+// ParseFieldRanges parses a comma-separated list of fzf field index
+// expressions: N, -N, N.., ..M, N..M and "..".
+func ParseFieldRanges(expr string) ([]FieldRange, error) {
+	var ranges []FieldRange
+	for _, part := range strings.Split(expr, ",") {
+		r, err := parseFieldRange(part)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseFieldRange(s string) (FieldRange, error) {
+	index := func(s string) (int, error) {
+		if s == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid field index %q", s)
+		}
+		return n, nil
+	}
+	from, to, isRange := strings.Cut(s, "..")
+	if !isRange {
+		if s == "" {
+			return FieldRange{}, fmt.Errorf("empty field index expression")
+		}
+		n, err := index(s)
+		return FieldRange{From: n, To: n}, err
+	}
+	f, err := index(from)
+	if err != nil {
+		return FieldRange{}, err
+	}
+	t, err := index(to)
+	return FieldRange{From: f, To: t}, err
+}
+
+// resolve turns r into a half-open range of token indices for a line
+// with n tokens; lo >= hi when it selects nothing.
+func (r FieldRange) resolve(n int) (lo, hi int) {
+	abs := func(i, open int) int {
+		switch {
+		case i == 0:
+			return open
+		case i < 0:
+			return n + i + 1
+		}
+		return i
+	}
+	lo = max(abs(r.From, 1), 1) - 1
+	hi = min(abs(r.To, n), n)
+	return lo, hi
+}
+
+// Delimiter splits a line into fields. nil means fzf's AWK-style default:
+// fields are separated by runs of spaces and tabs, and leading whitespace
+// is not part of any field.
+type Delimiter struct {
+	re *regexp.Regexp
+}
+
+// ParseDelimiter builds the delimiter for --delimiter. Like fzf, expr is a
+// regular expression, "\t" means a tab, and an expression that doesn't
+// compile is taken literally.
+func ParseDelimiter(expr string) *Delimiter {
+	expr = strings.ReplaceAll(expr, `\t`, "\t")
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		re = regexp.MustCompile(regexp.QuoteMeta(expr))
+	}
+	return &Delimiter{re: re}
+}
+
+// token is one field's byte span in the line. Each field but the last
+// ends with its delimiter, as in fzf; delim is where the delimiter starts.
+type token struct {
+	start, delim, end int
+}
+
+// tokenize splits text into fields.
+func (d *Delimiter) tokenize(text string) []token {
+	if d == nil {
+		return awkTokenize(text)
+	}
+	var tokens []token
+	start := 0
+	for _, loc := range d.re.FindAllStringIndex(text, -1) {
+		if loc[1] == loc[0] {
+			continue // empty matches don't split
+		}
+		tokens = append(tokens, token{start: start, delim: loc[0], end: loc[1]})
+		start = loc[1]
+	}
+	if start < len(text) {
+		tokens = append(tokens, token{start: start, delim: len(text), end: len(text)})
+	}
+	return tokens
+}
+
+func awkTokenize(text string) []token {
+	var tokens []token
+	i := 0
+	for i < len(text) && isBlank(text[i]) {
+		i++
+	}
+	for i < len(text) {
+		t := token{start: i}
+		for i < len(text) && !isBlank(text[i]) {
+			i++
+		}
+		t.delim = i
+		for i < len(text) && isBlank(text[i]) {
+			i++
+		}
+		t.end = i
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+func isBlank(c byte) bool { return c == ' ' || c == '\t' }
+
+// selectFields returns the byte spans of text covered by ranges, in range
+// order. Adjacent fields merge into one span, and the last span drops its
+// trailing delimiter ("path\tsize" with field 1 is "path", not "path\t").
+func selectFields(text string, d *Delimiter, ranges []FieldRange) [][2]int {
+	tokens := d.tokenize(text)
+	var spans [][2]int
+	last := -1
+	for _, r := range ranges {
+		lo, hi := r.resolve(len(tokens))
+		for i := lo; i < hi; i++ {
+			t := tokens[i]
+			last = i
+			if n := len(spans); n > 0 && spans[n-1][1] == t.start {
+				spans[n-1][1] = t.end
+				continue
+			}
+			spans = append(spans, [2]int{t.start, t.end})
+		}
+	}
+	if n := len(spans); n > 0 {
+		spans[n-1][1] = tokens[last].delim
+		if spans[n-1][0] == spans[n-1][1] {
+			spans = spans[:n-1]
+		}
+	}
+	return spans
+}
+
+// joinFields concatenates the selected spans of text: the --with-nth
+// display text. styles, when text has any (see markup.ParseANSI), are cut
+// to the same fields.
+func joinFields(text string, styles []markup.Span, d *Delimiter, ranges []FieldRange) (string, []markup.Span) {
+	spans := selectFields(text, d, ranges)
+	var joined []markup.Span
+	if styles != nil {
+		for _, s := range spans {
+			joined = append(joined, markup.Slice(styles, s[0], s[1])...)
+		}
+	}
+	if len(spans) == 1 {
+		return text[spans[0][0]:spans[0][1]], joined
+	}
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(text[s[0]:s[1]])
+	}
+	return b.String(), joined
+}
+
+// SearchText is the part of an item's Text that --nth restricts matching
+// to, with the same precomputed forms as Item. Highlight positions found
+// in it are rune indices into SearchText.Text; ToItem maps them back onto
+// Item.Text.
+type SearchText struct {
+	Text      string
+	LowerText string
+	ASCII     bool
+	Norm      *Normalized
+	Offset    int     // rune index in Item.Text where Text starts; used when Map is nil
+	Map       []int32 // rune index in Text → rune index in Item.Text; nil when Text is one contiguous run
+}
+
+// newSearchText builds the --nth search form of text, or nil when the
+// selected fields are all of it.
+func newSearchText(text string, d *Delimiter, ranges []FieldRange) *SearchText {
+	spans := selectFields(text, d, ranges)
+	if len(spans) == 1 && spans[0][0] == 0 && spans[0][1] == len(text) {
+		return nil
+	}
+	s := &SearchText{}
+	switch len(spans) {
+	case 0:
+		// Nothing to search: the item can only match the empty query.
+	case 1:
+		s.Text = text[spans[0][0]:spans[0][1]]
+		s.Offset = utf8.RuneCountInString(text[:spans[0][0]])
+	default:
+		var b strings.Builder
+		for _, sp := range spans {
+			r := int32(utf8.RuneCountInString(text[:sp[0]]))
+			for range text[sp[0]:sp[1]] {
+				s.Map = append(s.Map, r)
+				r++
+			}
+			b.WriteString(text[sp[0]:sp[1]])
+		}
+		s.Text = b.String()
+	}
+	s.ASCII = isASCII(s.Text)
+	if s.ASCII {
+		s.LowerText = asciiToLower(s.Text)
+	} else {
+		s.LowerText = strings.ToLower(s.Text)
+		s.Norm = normalize(s.Text, s.LowerText)
+	}
+	return s
+}
+
+// ToItem rewrites rune indices into s.Text as rune indices into Item.Text,
+// in place.
+func (s *SearchText) ToItem(positions []int) []int {
+	for i, p := range positions {
+		if s.Map != nil {
+			positions[i] = int(s.Map[p])
+		} else {
+			positions[i] = p + s.Offset
+		}
+	}
+	return positions
+}
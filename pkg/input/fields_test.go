@@ -0,0 +1,145 @@
+package input
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldRanges(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []FieldRange
+	}{
+		{"1", []FieldRange{{1, 1}}},
+		{"-1", []FieldRange{{-1, -1}}},
+		{"2..", []FieldRange{{2, 0}}},
+		{"..3", []FieldRange{{0, 3}}},
+		{"2..-2", []FieldRange{{2, -2}}},
+		{"..", []FieldRange{{0, 0}}},
+		{"1,3", []FieldRange{{1, 1}, {3, 3}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFieldRanges(tt.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "0", "a", "1,", "1..x", "1...2"} {
+		if _, err := ParseFieldRanges(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestJoinFields(t *testing.T) {
+	tab := ParseDelimiter(`\t`)
+	tests := []struct {
+		text  string
+		delim *Delimiter
+		expr  string
+		want  string
+	}{
+		{"src/main.go\t1024\t2024-01-02", tab, "1", "src/main.go"},
+		{"src/main.go\t1024\t2024-01-02", tab, "2..", "1024\t2024-01-02"},
+		{"src/main.go\t1024\t2024-01-02", tab, "-1", "2024-01-02"},
+		{"src/main.go\t1024\t2024-01-02", tab, "1,3", "src/main.go\t2024-01-02"},
+		{"a:b:c", ParseDelimiter(":"), "..2", "a:b"},
+		{"  one  two three", nil, "2", "two"},
+		{"  one  two three", nil, "1,3", "one  three"},
+		{"one two", nil, "5", ""},
+	}
+	for _, tt := range tests {
+		ranges, err := ParseFieldRanges(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := joinFields(tt.text, nil, tt.delim, ranges); got != tt.want {
+			t.Errorf("%q with %q: got %q, want %q", tt.text, tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseDelimiter_InvalidRegexIsLiteral(t *testing.T) {
+	d := ParseDelimiter("[")
+	if got, _ := joinFields("a[b[c", nil, d, []FieldRange{{2, 2}}); got != "b" {
+		t.Errorf("got %q, want %q", got, "b")
+	}
+}
+
+func TestParseLineWithOptions_Fields(t *testing.T) {
+	line := "src/main.go\t1024\t2024-01-02"
+	opts := ParseOptions{
+		Delimiter: ParseDelimiter(`\t`),
+		Nth:       []FieldRange{{1, 1}},
+		WithNth:   []FieldRange{{1, 2}},
+	}
+	item := ParseLineWithOptions(line, 7, opts)
+
+	if item.Raw != line {
+		t.Errorf("Raw = %q, want the input line", item.Raw)
+	}
+	if item.Text != "src/main.go\t1024" {
+		t.Errorf("Text = %q, want the --with-nth fields", item.Text)
+	}
+	if item.Search == nil || item.Search.Text != "src/main.go" {
+		t.Fatalf("Search = %+v, want the --nth field", item.Search)
+	}
+	if item.Search.Map != nil || item.Search.Offset != 0 {
+		t.Errorf("a single leading field needs no position map: %+v", item.Search)
+	}
+}
+
+// With --ansi, fields are split on the visible text: a delimiter inside
+// an escape ("1;31") doesn't split, and each field keeps its colors.
+func TestParseLineWithOptions_ANSIFields(t *testing.T) {
+	line := "\x1b[1;31mred\x1b[0m;\x1b[34mblue\x1b[0m;green"
+	item := ParseLineWithOptions(line, 0, ParseOptions{
+		ANSI:      true,
+		Delimiter: ParseDelimiter(";"),
+		Nth:       []FieldRange{{2, 2}},
+		WithNth:   []FieldRange{{1, 2}},
+	})
+	if item.Raw != line {
+		t.Errorf("Raw = %q, want the line with its escapes", item.Raw)
+	}
+	if item.Text != "red;blue" {
+		t.Fatalf("Text = %q, want the first two visible fields", item.Text)
+	}
+	var texts []string
+	for _, sp := range item.Spans {
+		texts = append(texts, sp.Text)
+	}
+	if !reflect.DeepEqual(texts, []string{"red", ";", "blue"}) || !item.Spans[0].Bold || item.Spans[2].FG == nil || item.Spans[1].FG != nil {
+		t.Errorf("Spans = %+v, want bold red, a plain delimiter, blue", item.Spans)
+	}
+	if item.Search == nil || item.Search.Text != "blue" || item.Search.Offset != 4 {
+		t.Errorf("Search = %+v, want field 2 of the displayed text", item.Search)
+	}
+}
+
+func TestSearchText_ToItem(t *testing.T) {
+	// Fields 1 and 3 of "ab cd éf": search text "ab éf" (5 runes).
+	s := newSearchText("ab cd éf", nil, []FieldRange{{1, 1}, {3, 3}})
+	if s.Text != "ab éf" {
+		t.Fatalf("Text = %q", s.Text)
+	}
+	if got := s.ToItem([]int{0, 1, 3, 4}); !reflect.DeepEqual(got, []int{0, 1, 6, 7}) {
+		t.Errorf("ToItem = %v, want [0 1 6 7]", got)
+	}
+
+	s = newSearchText("ab cd éf", nil, []FieldRange{{2, 0}})
+	if s.Text != "cd éf" || s.Offset != 3 {
+		t.Fatalf("got %q at %d", s.Text, s.Offset)
+	}
+	if got := s.ToItem([]int{3}); !reflect.DeepEqual(got, []int{6}) {
+		t.Errorf("ToItem = %v, want [6]", got)
+	}
+
+	if s := newSearchText("ab cd", nil, []FieldRange{{0, 0}}); s != nil {
+		t.Errorf("selecting every field should need no search text, got %+v", s)
+	}
+}
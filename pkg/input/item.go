@@ -13,6 +13,10 @@ type Item struct {
 	Raw    string // Original input line, returned verbatim to the caller on selection (markup intact when Spans is non-nil)
 	Index  int    // Original order from stdin
 	Spans  []markup.Span // Styled runs covering Text; nil when markup is disabled or parse fell back.
+	// Priority is the producer's hint of the item's importance, from a
+	// "plugin priority=N" header (e.g. a running app or a pinned bookmark).
+	// Higher comes first; 0 (the default) is neutral and negatives sink.
+	Priority float64
 
 	// LowerText is Text lowercased once at parse time so per-keystroke matching
 	// doesn't pay the strings.ToLower allocation per item per call.
@@ -21,16 +25,35 @@ type Item struct {
 	// ASCII is true when Text is pure ASCII; lets the matcher take a byte-level
 	// fast path that avoids []rune conversion (the dominant cost on large inputs).
 	ASCII bool
+	// Norm holds the diacritic-insensitive search forms of Text. nil for
+	// ASCII text and whenever normalization wouldn't change anything, so
+	// the common case costs one pointer per item.
+	Norm *Normalized
+	// Search restricts matching to the --nth fields of Text. nil (the
+	// default) matches against all of Text.
+	Search *SearchText
+	// Mask is the CharMask of every form of Text the matcher searches
+	// (original, lowercased, normalized), so the matcher can reject items
+	// missing a query character without searching them. 0 when unknown.
+	Mask uint64
 }
 
-// Init populates LowerText and ASCII from Text. Reader calls this; tests that
-// build Items by hand can call it (or leave it — matcher falls back gracefully).
+// Init populates LowerText, ASCII, Norm and Mask from Text. Reader calls this; tests
+// that build Items by hand can call it (or leave it — matcher falls back
+// gracefully).
 func (i *Item) Init() {
 	i.ASCII = isASCII(i.Text)
 	if i.ASCII {
 		i.LowerText = asciiToLower(i.Text)
+		i.Norm = nil
+		i.Mask = CharMask(i.Text) // ASCII letters share a bit across case
 	} else {
 		i.LowerText = strings.ToLower(i.Text)
+		i.Norm = normalize(i.Text, i.LowerText)
+		i.Mask = CharMask(i.Text) | CharMask(i.LowerText)
+		if i.Norm != nil {
+			i.Mask |= CharMask(i.Norm.Text) | CharMask(i.Norm.Lower)
+		}
 	}
 }
 
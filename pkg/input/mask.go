@@ -0,0 +1,33 @@
+package input
+
+// CharMask is a 64-bit set of the characters in s: one bit per ASCII
+// letter (either case) and digit, the rest hashed onto the remaining
+// bits. A query can only match text whose mask contains the query's, so
+// one AND rejects most items before any string search.
+func CharMask(s string) uint64 {
+	var mask uint64
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x80 {
+			mask |= 1 << charBit(rune(c))
+			continue
+		}
+		// Rare: finish the string rune by rune.
+		for _, r := range s[i:] {
+			mask |= 1 << charBit(r)
+		}
+		break
+	}
+	return mask
+}
+
+func charBit(r rune) uint {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return uint(r - 'a')
+	case r >= 'A' && r <= 'Z':
+		return uint(r - 'A')
+	case r >= '0' && r <= '9':
+		return 26 + uint(r-'0')
+	}
+	return 36 + uint(r)%28
+}
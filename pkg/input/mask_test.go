@@ -0,0 +1,31 @@
+package input
+
+import "testing"
+
+func TestCharMask(t *testing.T) {
+	if CharMask("Foo") != CharMask("oof") {
+		t.Error("ASCII letters should share a bit across case")
+	}
+	if CharMask("") != 0 {
+		t.Error("empty string should have an empty mask")
+	}
+	if m := CharMask("ab"); m&^CharMask("cab") != 0 {
+		t.Error("mask of a subset should be contained in the superset's")
+	}
+	if CharMask("a1") == CharMask("a2") {
+		t.Error("digits should have bits of their own")
+	}
+	if CharMask("aé") != CharMask("a")|CharMask("é") {
+		t.Error("non-ASCII runes should be hashed after the ASCII prefix")
+	}
+}
+
+func TestItemInit_MaskCoversSearchForms(t *testing.T) {
+	item := Item{Text: "Émile Straße"}
+	item.Init()
+	for _, s := range []string{"émile", "Émile", "emile", "strasse", "STRASSE"} {
+		if m := CharMask(s); m&^item.Mask != 0 {
+			t.Errorf("Mask of %q is missing characters of %q", item.Text, s)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package input
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalized holds the diacritic-insensitive search forms of an item's Text.
+// Built once at parse time so typing plain ASCII ("cafe", "naive") finds
+// "Café" and "naïve" without per-keystroke Unicode work.
+//
+// Rune counts can change during normalization (ligatures expand, ß folds to
+// "ss"), so each form carries a map from its rune indices back to rune
+// indices in Item.Text — highlight positions must always land on Text.
+type Normalized struct {
+	Text     string  // NFKD, combining marks stripped, case preserved
+	TextMap  []int32 // rune index in Text → rune index in Item.Text; nil when identity
+	Lower    string  // Text with full Unicode case folding (ß → ss)
+	LowerMap []int32 // rune index in Lower → rune index in Item.Text; nil when identity
+	ASCII    bool    // both forms are pure ASCII, so the matcher's byte fast path applies
+}
+
+// normalize builds the Normalized forms for a non-ASCII text. Returns nil
+// when normalization changes nothing (e.g. CJK text), so the matcher can
+// keep using Text/LowerText and no extra memory is spent.
+func normalize(text, lowerText string) *Normalized {
+	folder := cases.Fold()
+	var (
+		plain, folded       []rune
+		plainMap, foldedMap []int32
+		buf                 [utf8.UTFMax]byte
+	)
+	idx := int32(0)
+	for _, r := range text {
+		for _, d := range decompose(r, buf[:0]) {
+			plain = append(plain, d)
+			plainMap = append(plainMap, idx)
+			for _, f := range foldRune(folder, d) {
+				folded = append(folded, f)
+				foldedMap = append(foldedMap, idx)
+			}
+		}
+		idx++
+	}
+
+	n := &Normalized{Text: string(plain), Lower: string(folded)}
+	if n.Text == text && n.Lower == lowerText {
+		return nil
+	}
+	if len(plain) != int(idx) || !isIdentity(plainMap) {
+		n.TextMap = plainMap
+	}
+	if len(folded) != int(idx) || !isIdentity(foldedMap) {
+		n.LowerMap = foldedMap
+	}
+	n.ASCII = isASCII(n.Text) && isASCII(n.Lower)
+	return n
+}
+
+// Normalize returns the search form of s: NFKD with combining marks
+// stripped, plus full case folding when fold is set. The matcher applies
+// it to queries so they compare against Normalized.Text / Lower.
+func Normalize(s string, fold bool) string {
+	if isASCII(s) {
+		if fold {
+			return asciiToLower(s)
+		}
+		return s
+	}
+	var (
+		b   strings.Builder
+		buf [utf8.UTFMax]byte
+	)
+	folder := cases.Fold()
+	for _, r := range s {
+		for _, d := range decompose(r, buf[:0]) {
+			if fold {
+				for _, f := range foldRune(folder, d) {
+					b.WriteRune(f)
+				}
+			} else {
+				b.WriteRune(d)
+			}
+		}
+	}
+	return b.String()
+}
+
+// decompose returns r's compatibility decomposition without combining
+// marks: "é" → "e", "ﬁ" → "fi", "Ａ" → "A". ASCII runes pass through.
+func decompose(r rune, scratch []byte) []rune {
+	if r < utf8.RuneSelf {
+		return []rune{r}
+	}
+	scratch = utf8.AppendRune(scratch, r)
+	out := make([]rune, 0, 2)
+	for _, d := range string(norm.NFKD.Bytes(scratch)) {
+		if unicode.Is(unicode.Mn, d) {
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// foldRune applies full case folding to a single rune. Folding is context
+// free, so doing it rune by rune is equivalent to folding the string and
+// lets us keep the index map exact.
+func foldRune(folder cases.Caser, r rune) []rune {
+	if r < utf8.RuneSelf {
+		if r >= 'A' && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+		return []rune{r}
+	}
+	return []rune(folder.String(string(r)))
+}
+
+func isIdentity(m []int32) bool {
+	for i, v := range m {
+		if int32(i) != v {
+			return false
+		}
+	}
+	return true
+}
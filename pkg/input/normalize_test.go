@@ -0,0 +1,80 @@
+package input
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestItemInit_NormalizesDiacritics(t *testing.T) {
+	item := Item{Text: "Café naïve"}
+	item.Init()
+
+	if item.Norm == nil {
+		t.Fatal("expected Norm to be populated for accented text")
+	}
+	if item.Norm.Text != "Cafe naive" {
+		t.Errorf("Norm.Text = %q, want %q", item.Norm.Text, "Cafe naive")
+	}
+	if item.Norm.Lower != "cafe naive" {
+		t.Errorf("Norm.Lower = %q, want %q", item.Norm.Lower, "cafe naive")
+	}
+	if !item.Norm.ASCII {
+		t.Error("expected normalized forms to be ASCII")
+	}
+	// Stripping combining marks keeps one rune per original rune here.
+	if item.Norm.TextMap != nil || item.Norm.LowerMap != nil {
+		t.Error("expected identity maps to be elided")
+	}
+}
+
+func TestItemInit_FullCaseFoldingMapsBack(t *testing.T) {
+	item := Item{Text: "Straße"}
+	item.Init()
+
+	if item.Norm == nil || item.Norm.Lower != "strasse" {
+		t.Fatalf("Norm = %+v, want Lower %q", item.Norm, "strasse")
+	}
+	want := []int32{0, 1, 2, 3, 4, 4, 5}
+	if !reflect.DeepEqual(item.Norm.LowerMap, want) {
+		t.Errorf("LowerMap = %v, want %v", item.Norm.LowerMap, want)
+	}
+}
+
+func TestItemInit_FullWidth(t *testing.T) {
+	item := Item{Text: "ＦＯＯ.txt"}
+	item.Init()
+
+	if item.Norm == nil || item.Norm.Lower != "foo.txt" {
+		t.Fatalf("Norm = %+v, want Lower %q", item.Norm, "foo.txt")
+	}
+}
+
+func TestItemInit_NoNormForUnchangedText(t *testing.T) {
+	for _, text := range []string{"plain ascii", "日本語のファイル"} {
+		item := Item{Text: text}
+		item.Init()
+		if item.Norm != nil {
+			t.Errorf("%q: expected nil Norm, got %+v", text, item.Norm)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		fold bool
+		want string
+	}{
+		{"Café", false, "Cafe"},
+		{"Café", true, "cafe"},
+		{"STRASSE", true, "strasse"},
+		{"Straße", true, "strasse"},
+		{"ﬁle", false, "file"},
+		{"plain", true, "plain"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in, tt.fold); got != tt.want {
+			t.Errorf("Normalize(%q, %v) = %q, want %q", tt.in, tt.fold, got, tt.want)
+		}
+	}
+}
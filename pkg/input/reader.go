@@ -2,14 +2,21 @@ package input
 
 import (
 	"bufio"
+	"bytes"
 	"io"
+	"math"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/sam33r/goose-launcher/pkg/markup"
 )
 
 const separator = "   . " // 3 spaces + dot + space
 
+// newlineSymbol stands in for line breaks inside an item's displayed text.
+const newlineSymbol = '\u2424'
+
 // Reader reads and parses items from stdin
 type Reader struct {
 	scanner *bufio.Scanner
@@ -25,31 +32,82 @@ func NewReader(r io.Reader, markupFormat string) *Reader {
 	}
 }
 
+// ScanNul is a bufio.SplitFunc for NUL-terminated items. Unlike
+// bufio.ScanLines it leaves items intact: newlines and carriage returns
+// are part of the item. A final item without a NUL is still returned.
+func ScanNul(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// ParseOptions control how ParseLine turns a line into an Item.
+type ParseOptions struct {
+	Markup    string       // stdin markup format: "" (off) or "pango"
+	ANSI      bool         // parse ANSI color escapes (exclusive with Markup)
+	Delimiter *Delimiter   // field delimiter for Nth and WithNth; nil is AWK-style
+	Nth       []FieldRange // fields matching is limited to; nil means all of Text
+	WithNth   []FieldRange // fields shown (and searched); nil shows the whole line
+}
+
 // ParseLine parses a single line into an Item.
-// Format: "plugin   . item_text" or just "item_text".
+// Format: "plugin   . item_text" or just "item_text". The plugin header
+// may end in a "priority=N" key ("apps priority=10   . Firefox", or
+// "priority=10   . Firefox" without a plugin) to set Item.Priority.
 // markupFormat selects stdin markup parsing; pass "" to disable.
 //
 // Used directly by the daemon's streaming chunk handler so it can parse lines
 // as they arrive without holding a Reader. Reader.ReadAll delegates here too.
 func ParseLine(line string, index int, markupFormat string) Item {
+	return ParseLineWithOptions(line, index, ParseOptions{Markup: markupFormat})
+}
+
+// ParseLineWithOptions is ParseLine with field selection. WithNth replaces
+// the displayed Text with the chosen fields, split before Pango markup is
+// parsed but after ANSI escapes are (escapes aren't text a delimiter
+// should see, and a field keeps its colors); Nth then picks the fields of
+// that Text the matcher sees (so highlight positions still land on the
+// displayed text). Raw is always the line as read — it's what selection
+// prints.
+func ParseLineWithOptions(line string, index int, opts ParseOptions) Item {
 	parts := strings.SplitN(line, separator, 2)
 
 	var plugin, text string
+	var priority float64
 	if len(parts) == 2 {
-		plugin = strings.TrimSpace(parts[0])
+		plugin, priority = splitPriority(strings.TrimSpace(parts[0]))
 		text = parts[1]
 	} else {
 		text = line
 	}
+	var spans []markup.Span
+	if opts.ANSI {
+		// Item.Raw keeps the escapes, so the selection prints exactly
+		// what the producer wrote.
+		text, spans = markup.ParseANSI(text)
+	}
+	if opts.WithNth != nil {
+		text, spans = joinFields(text, spans, opts.Delimiter, opts.WithNth)
+	}
+	text = displayText(text)
+	for i := range spans {
+		spans[i].Text = displayText(spans[i].Text)
+	}
 
 	item := Item{
-		Plugin: plugin,
-		Text:   text,
-		Raw:    line,
-		Index:  index,
+		Plugin:   plugin,
+		Text:     text,
+		Raw:      line,
+		Index:    index,
+		Priority: priority,
+		Spans:    spans,
 	}
 
-	if markupFormat == "pango" {
+	if opts.Markup == "pango" {
 		// Parse the text portion for display. On failure fall back to the
 		// literal line — one bad item shouldn't break the whole launcher.
 		// item.Raw stays as the original input line so the caller gets the
@@ -63,9 +121,86 @@ func ParseLine(line string, index int, markupFormat string) Item {
 	}
 
 	item.Init()
+	if opts.Nth != nil {
+		item.Search = newSearchText(item.Text, opts.Delimiter, opts.Nth)
+	}
 	return item
 }
 
+// displayText is text as shown and searched. Raw keeps the bytes (a
+// Latin-1 file name must come back as is); the display marks each byte
+// that isn't UTF-8. A --read0 item spanning lines shows on one row, "␤"
+// marking each break, and other control characters but tab show as their
+// Unicode control pictures ("\r" as "␍"): one rune for another, so
+// positions still line up.
+func displayText(text string) string {
+	if !utf8.ValidString(text) {
+		text = toValidUTF8(text)
+	}
+	if strings.IndexFunc(text, isControl) >= 0 {
+		text = strings.Map(controlPicture, text)
+	}
+	return text
+}
+
+// isControl reports whether r is a C0 control character other than tab,
+// which displayText replaces.
+func isControl(r rune) bool {
+	return r < 0x20 && r != '\t'
+}
+
+// controlPicture maps a control character to the symbol shown for it.
+func controlPicture(r rune) rune {
+	switch {
+	case r == '\n':
+		return newlineSymbol
+	case isControl(r):
+		return 0x2400 + r // ␀ through ␟
+	}
+	return r
+}
+
+// toValidUTF8 replaces each byte of s that isn't part of a valid UTF-8
+// sequence with U+FFFD. Unlike strings.ToValidUTF8 it doesn't merge runs
+// of them, so a name's length on screen matches its byte count.
+func toValidUTF8(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteRune(utf8.RuneError)
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// priorityKey introduces an item's priority in the plugin header.
+const priorityKey = "priority="
+
+// splitPriority splits a "plugin priority=N" header ("priority=N" alone
+// without a plugin). Only the explicit key sets a priority, so a plugin
+// named "python@3" keeps its name; so does one whose key isn't followed
+// by a finite number.
+func splitPriority(header string) (plugin string, priority float64) {
+	field := header
+	if sp := strings.LastIndexByte(header, ' '); sp >= 0 {
+		plugin, field = strings.TrimRight(header[:sp], " "), header[sp+1:]
+	}
+	value, ok := strings.CutPrefix(field, priorityKey)
+	if !ok {
+		return header, 0
+	}
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(p) || math.IsInf(p, 0) {
+		return header, 0
+	}
+	return plugin, p
+}
+
 // parseLine is kept as a thin method-receiver shim so existing tests
 // (TestParseLine_*) continue to call r.parseLine(line, index).
 func (r *Reader) parseLine(line string, index int) Item {
@@ -1,6 +1,7 @@
 package input
 
 import (
+	"bufio"
 	"strings"
 	"testing"
 )
@@ -42,6 +43,34 @@ func TestParseLine_WithoutSeparator(t *testing.T) {
 	}
 }
 
+func TestParseLine_Priority(t *testing.T) {
+	tests := []struct {
+		line     string
+		plugin   string
+		priority float64
+	}{
+		{"apps priority=10   . Firefox", "apps", 10},
+		{"priority=2.5   . Firefox", "", 2.5},
+		{"bookmarks priority=-1   . old", "bookmarks", -1},
+		{"my apps  priority=3   . Firefox", "my apps", 3},
+		{"python@3   . venv", "python@3", 0}, // no key: all plugin name
+		{"node@18   . repl", "node@18", 0},
+		{"user@host   . ssh", "user@host", 0},
+		{"apps priority=NaN   . Firefox", "apps priority=NaN", 0},
+		{"apps priority=   . Firefox", "apps priority=", 0},
+		{"x priority=10 no separator", "", 0},
+	}
+	for _, tt := range tests {
+		item := ParseLine(tt.line, 0, "")
+		if item.Plugin != tt.plugin || item.Priority != tt.priority {
+			t.Errorf("ParseLine(%q): plugin %q, priority %v; want %q, %v", tt.line, item.Plugin, item.Priority, tt.plugin, tt.priority)
+		}
+		if item.Raw != tt.line {
+			t.Errorf("ParseLine(%q): raw %q, want the line verbatim", tt.line, item.Raw)
+		}
+	}
+}
+
 func TestReadAll(t *testing.T) {
 	input := `files   . /home/user/file1.txt
 files   . /home/user/file2.txt
@@ -178,3 +207,64 @@ func TestReadAll_EmptyInput(t *testing.T) {
 		t.Errorf("expected 0 items, got %d", len(items))
 	}
 }
+
+// --read0 as the client runs it: stdin split by ScanNul, each item
+// parsed (by the daemon) with ParseLine.
+func TestScanNul(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("a file\x00two\nlines\r\x00\x00last\x1b\tcol\x07"))
+	scanner.Split(ScanNul)
+	var items []Item
+	for scanner.Scan() {
+		items = append(items, ParseLine(scanner.Text(), len(items), ""))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"a file", "two\nlines\r", "", "last\x1b\tcol\x07"}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for i, raw := range want {
+		if items[i].Raw != raw {
+			t.Errorf("item %d raw %q, want %q", i, items[i].Raw, raw)
+		}
+	}
+	// Line breaks and other control characters but tab show as symbols;
+	// selection still prints them.
+	if items[1].Text != "two\u2424lines\u240d" {
+		t.Errorf("item 1 text %q, want its newline and carriage return shown as symbols", items[1].Text)
+	}
+	if items[3].Text != "last\u241b\tcol\u2407" {
+		t.Errorf("item 3 text %q, want ESC and BEL shown as symbols, tab kept", items[3].Text)
+	}
+}
+
+func TestParseLine_InvalidUTF8(t *testing.T) {
+	line := "files   . caf\xe9\xe8.txt" // Latin-1 "cafée.txt"
+	item := ParseLine(line, 0, "")
+	if item.Raw != line {
+		t.Errorf("raw %q, want the bytes as read", item.Raw)
+	}
+	if item.Text != "caf\ufffd\ufffd.txt" {
+		t.Errorf("text %q, want one U+FFFD per invalid byte", item.Text)
+	}
+}
+
+func TestParseLineWithOptions_ANSI(t *testing.T) {
+	line := "\x1b[33mabc1234\x1b[m fix the \x1b[1mbuild\x1b[0m"
+	item := ParseLineWithOptions(line, 0, ParseOptions{ANSI: true})
+	if item.Text != "abc1234 fix the build" {
+		t.Errorf("Text = %q, want the escapes stripped", item.Text)
+	}
+	if item.Raw != line {
+		t.Errorf("Raw should keep the escapes, got %q", item.Raw)
+	}
+	if len(item.Spans) != 3 || item.Spans[0].FG == nil || !item.Spans[2].Bold {
+		t.Errorf("Spans = %+v, want a colored hash and a bold word", item.Spans)
+	}
+
+	// Off by default: the escapes are literal text, ESC shown as a symbol.
+	if off := ParseLine(line, 0, ""); off.Text != strings.ReplaceAll(line, "\x1b", "\u241b") || off.Spans != nil {
+		t.Errorf("without ANSI: Text %q, Spans %+v", off.Text, off.Spans)
+	}
+}
@@ -0,0 +1,237 @@
+package markup
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ParseANSI returns the plain text of s with ANSI escape sequences
+// stripped, and spans styled by its SGR sequences (ESC [ … m): bold,
+// italic, underline, and 16-color, 256-color or truecolor foreground and
+// background. Other escape sequences (cursor movement, OSC hyperlinks)
+// are dropped. Unlike Parse it can't fail: a terminal shows whatever text
+// is left, and so do we.
+//
+// Text without an escape comes back as is, with nil spans.
+func ParseANSI(s string) (plain string, spans []Span) {
+	if !strings.Contains(s, "\x1b") {
+		return s, nil
+	}
+	var (
+		plainBuf strings.Builder
+		style    Span
+	)
+	plainBuf.Grow(len(s))
+	for len(s) > 0 {
+		esc := strings.IndexByte(s, '\x1b')
+		if esc < 0 {
+			esc = len(s)
+		}
+		if esc > 0 {
+			plainBuf.WriteString(s[:esc])
+			spans = appendSpan(spans, s[:esc], style)
+		}
+		s = s[esc:]
+		if len(s) == 0 {
+			break
+		}
+		var params string
+		var sgr bool
+		params, sgr, s = cutEscape(s)
+		if sgr {
+			style = applySGR(style, params)
+		}
+	}
+	return plainBuf.String(), spans
+}
+
+// cutEscape splits the escape sequence off the front of s, which starts
+// with ESC. It reports the parameters of an SGR sequence; a truncated
+// sequence runs to the end of s.
+func cutEscape(s string) (params string, sgr bool, rest string) {
+	if len(s) < 2 {
+		return "", false, ""
+	}
+	switch s[1] {
+	case '[': // CSI: parameter and intermediate bytes, then a final byte
+		for i := 2; i < len(s); i++ {
+			if c := s[i]; c >= 0x40 && c <= 0x7E {
+				return s[2:i], c == 'm', s[i+1:]
+			}
+		}
+		return "", false, ""
+	case ']': // OSC: ends at BEL or ESC \
+		for i := 2; i < len(s); i++ {
+			switch {
+			case s[i] == '\a':
+				return "", false, s[i+1:]
+			case s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\':
+				return "", false, s[i+2:]
+			}
+		}
+		return "", false, ""
+	}
+	return "", false, s[2:] // a two-byte sequence such as ESC =
+}
+
+// applySGR layers an SGR sequence's parameters over style. Codes we
+// don't render (dim, blink, reverse…) are skipped.
+func applySGR(style Span, params string) Span {
+	fields := strings.Split(params, ";")
+	for i := 0; i < len(fields); i++ {
+		// A field may carry colon sub-parameters: "4:3" (curly underline)
+		// or "38:2::255:0:0" (ITU truecolor, color space left empty).
+		sub := strings.Split(fields[i], ":")
+		switch c := sgrCode(sub[0]); {
+		case c == 0:
+			style = Span{}
+		case c == 1:
+			style.Bold = true
+		case c == 3:
+			style.Italic = true
+		case c == 4:
+			style.Underline = len(sub) == 1 || sgrCode(sub[1]) != 0
+		case c == 22:
+			style.Bold = false
+		case c == 23:
+			style.Italic = false
+		case c == 24:
+			style.Underline = false
+		case c >= 30 && c <= 37:
+			style.FG = ansiColor(c - 30)
+		case c >= 90 && c <= 97:
+			style.FG = ansiColor(c - 90 + 8)
+		case c == 39:
+			style.FG = nil
+		case c >= 40 && c <= 47:
+			style.BG = ansiColor(c - 40)
+		case c >= 100 && c <= 107:
+			style.BG = ansiColor(c - 100 + 8)
+		case c == 49:
+			style.BG = nil
+		case c == 38 || c == 48:
+			var col *color.NRGBA
+			if len(sub) > 1 {
+				args := sgrCodes(sub[1:])
+				if len(args) == 5 && args[0] == 2 {
+					args = append(args[:1], args[2:]...) // drop the color space
+				}
+				col, _ = extendedColor(args)
+			} else {
+				var n int
+				col, n = extendedColor(sgrCodes(fields[i+1:]))
+				i += n
+			}
+			if col == nil {
+				continue
+			}
+			if c == 38 {
+				style.FG = col
+			} else {
+				style.BG = col
+			}
+		}
+	}
+	return style
+}
+
+// sgrCodes parses SGR parameters with sgrCode.
+func sgrCodes(fields []string) []int {
+	codes := make([]int, len(fields))
+	for i, f := range fields {
+		codes[i] = sgrCode(f)
+	}
+	return codes
+}
+
+// sgrCode parses one SGR parameter. An empty one (as in "ESC [ m" or
+// "1;;4") is 0, and a malformed one -1, which no code matches.
+func sgrCode(f string) int {
+	if f == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(f)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// extendedColor reads the color after a 38 or 48 code: "5;N" indexes the
+// 256-color palette, "2;R;G;B" is truecolor. It returns nil for a missing
+// or out-of-range color, and how many codes it consumed.
+func extendedColor(codes []int) (*color.NRGBA, int) {
+	if len(codes) == 0 {
+		return nil, 0
+	}
+	switch codes[0] {
+	case 5:
+		if len(codes) < 2 {
+			return nil, len(codes)
+		}
+		if n := codes[1]; n >= 0 && n <= 255 {
+			return ansiColor(n), 2
+		}
+		return nil, 2
+	case 2:
+		if len(codes) < 4 {
+			return nil, len(codes)
+		}
+		rgb := codes[1:4]
+		for _, v := range rgb {
+			if v < 0 || v > 255 {
+				return nil, 4
+			}
+		}
+		return &color.NRGBA{R: uint8(rgb[0]), G: uint8(rgb[1]), B: uint8(rgb[2]), A: 0xFF}, 4
+	}
+	return nil, 1
+}
+
+// ansi16 is the basic and bright palette, codes 30–37 and 90–97. The
+// basic colors are the named ones Parse accepts, so the two formats look
+// alike.
+var ansi16 = [16]color.NRGBA{
+	named["black"],
+	named["red"],
+	named["green"],
+	named["yellow"],
+	named["blue"],
+	named["magenta"],
+	named["cyan"],
+	{R: 0xCC, G: 0xCC, B: 0xCC, A: 0xFF}, // "white" is a light gray on terminals
+	named["gray"],
+	named["lightred"],
+	named["lightgreen"],
+	named["lightyellow"],
+	named["lightblue"],
+	named["lightmagenta"],
+	named["lightcyan"],
+	named["white"],
+}
+
+// ansiColor returns color n of the 256-color palette: the 16 above, a
+// 6×6×6 cube, then 24 grays, as xterm defines them.
+func ansiColor(n int) *color.NRGBA {
+	var c color.NRGBA
+	switch {
+	case n < 16:
+		c = ansi16[n]
+	case n < 232:
+		n -= 16
+		c = color.NRGBA{R: cubeLevel(n / 36), G: cubeLevel(n / 6 % 6), B: cubeLevel(n % 6), A: 0xFF}
+	default:
+		v := uint8(8 + 10*(n-232))
+		c = color.NRGBA{R: v, G: v, B: v, A: 0xFF}
+	}
+	return &c
+}
+
+// cubeLevel is the intensity of step i (0–5) of the color cube.
+func cubeLevel(i int) uint8 {
+	if i == 0 {
+		return 0
+	}
+	return uint8(55 + 40*i)
+}
@@ -0,0 +1,75 @@
+package markup
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseANSI_PlainText(t *testing.T) {
+	plain, spans := ParseANSI("hello world")
+	if plain != "hello world" || spans != nil {
+		t.Errorf("ParseANSI = %q, %+v; want the text back with no spans", plain, spans)
+	}
+}
+
+func TestParseANSI_Styles(t *testing.T) {
+	// What `git log --color --oneline` and `rg --color=always` emit.
+	plain, spans := ParseANSI("\x1b[33mabc1234\x1b[m \x1b[1;3;4mbold\x1b[22m still\x1b[0m plain")
+	if plain != "abc1234 bold still plain" {
+		t.Errorf("plain = %q", plain)
+	}
+	want := []Span{
+		{Text: "abc1234", FG: &ansi16[3]},
+		{Text: " "},
+		{Text: "bold", Bold: true, Italic: true, Underline: true},
+		{Text: " still", Italic: true, Underline: true},
+		{Text: " plain"},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("spans = %+v, want %d", spans, len(want))
+	}
+	for i := range want {
+		if spans[i].Text != want[i].Text || !sameStyle(spans[i], want[i]) {
+			t.Errorf("span[%d] = %+v, want %+v", i, spans[i], want[i])
+		}
+	}
+}
+
+func TestParseANSI_Colors(t *testing.T) {
+	rgb := func(r, g, b uint8) *color.NRGBA { return &color.NRGBA{R: r, G: g, B: b, A: 0xFF} }
+	tests := []struct {
+		seq    string
+		fg, bg *color.NRGBA
+	}{
+		{"\x1b[31m", &ansi16[1], nil},
+		{"\x1b[91;44m", &ansi16[9], &ansi16[4]},
+		{"\x1b[105m", nil, &ansi16[13]},
+		{"\x1b[38;5;208m", rgb(0xFF, 0x87, 0x00), nil},
+		{"\x1b[48;5;240m", nil, rgb(0x58, 0x58, 0x58)},
+		{"\x1b[38;5;4m", &ansi16[4], nil},
+		{"\x1b[38;2;10;20;30;48;2;1;2;3m", rgb(10, 20, 30), rgb(1, 2, 3)},
+		{"\x1b[38:2::10:20:30m", rgb(10, 20, 30), nil},
+		{"\x1b[38:5:208m", rgb(0xFF, 0x87, 0x00), nil},
+		{"\x1b[31;39m", nil, nil},
+		{"\x1b[38;5;300m", nil, nil}, // out of range: ignored
+	}
+	for _, tt := range tests {
+		_, spans := ParseANSI(tt.seq + "x")
+		if len(spans) != 1 {
+			t.Errorf("%q: spans = %+v, want one", tt.seq, spans)
+			continue
+		}
+		if !colorEq(spans[0].FG, tt.fg) || !colorEq(spans[0].BG, tt.bg) {
+			t.Errorf("%q: FG %v BG %v, want %v %v", tt.seq, spans[0].FG, spans[0].BG, tt.fg, tt.bg)
+		}
+	}
+}
+
+func TestParseANSI_StripsOtherSequences(t *testing.T) {
+	// `ls --hyperlink` wraps names in OSC 8 links; progress output moves
+	// the cursor. Neither is text, and a truncated sequence is dropped.
+	plain, _ := ParseANSI("\x1b]8;;file:///tmp/a\x1b\\a.txt\x1b]8;;\x07 \x1b[2Kdone\x1b[1")
+	if plain != "a.txt done" {
+		t.Errorf("plain = %q, want %q", plain, "a.txt done")
+	}
+}
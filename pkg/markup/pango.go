@@ -1,4 +1,5 @@
-// Package markup parses a small Pango-markup subset into styled text spans.
+// Package markup parses a small Pango-markup subset, or ANSI SGR escapes
+// (see ParseANSI), into styled text spans.
 //
 // We support the tags goose-launcher currently renders (<b>, <i>, fg color)
 // plus a couple we parse but don't render yet (<u>, bg color). Keeping the
@@ -143,6 +144,22 @@ func appendSpan(spans []Span, text string, style Span) []Span {
 	return append(spans, style)
 }
 
+// Slice returns the spans covering bytes [lo, hi) of the text spans
+// cover, cutting the spans at either end.
+func Slice(spans []Span, lo, hi int) []Span {
+	var out []Span
+	start := 0
+	for _, s := range spans {
+		end := start + len(s.Text)
+		if end > lo && start < hi {
+			s.Text = s.Text[max(lo, start)-start : min(hi, end)-start]
+			out = append(out, s)
+		}
+		start = end
+	}
+	return out
+}
+
 func sameStyle(a, b Span) bool {
 	return a.Bold == b.Bold &&
 		a.Italic == b.Italic &&
@@ -217,3 +217,15 @@ func TestParse_SpansConcatEqualsPlain(t *testing.T) {
 		}
 	}
 }
+
+func TestSlice(t *testing.T) {
+	red := &color.NRGBA{R: 0xFF, A: 0xFF}
+	spans := []Span{{Text: "ab", Bold: true}, {Text: "cde", FG: red}, {Text: "f"}}
+	got := Slice(spans, 1, 4)
+	if len(got) != 2 || got[0].Text != "b" || !got[0].Bold || got[1].Text != "cd" || got[1].FG != red {
+		t.Errorf("Slice(1, 4) = %+v, want bold b, red cd", got)
+	}
+	if got := Slice(spans, 5, 5); got != nil {
+		t.Errorf("empty Slice = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,127 @@
+package matcher
+
+import (
+	"strings"
+	"unicode"
+)
+
+// acronymTerm reports whether a term's text can be an initialism: two or
+// more letters and digits, nothing else. "gld" is meant as
+// goose-launcher-daemon; "g-l" or "main.go" never is.
+func acronymTerm(text string) bool {
+	n := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+		n++
+	}
+	return n >= 2
+}
+
+// isWordStart reports whether a char of class cur after one of class prev
+// begins a word: after whitespace, a delimiter or punctuation, at a
+// camelCase hump, or where digits start. The same boundaries earn a bonus
+// in the scoring model.
+func isWordStart(prev, cur charClass) bool {
+	return cur > charDelimiter && bonusFor(prev, cur) >= bonusCamel123
+}
+
+// matchAcronym matches searchQuery against the word-initial characters of
+// text only, so "gld" finds goose-launcher-daemon and "fb" FooBar.go. It
+// backs up the greedy fuzzy walk, which takes the first "l" and "d" it
+// sees, and lets exact mode accept initialisms. Word boundaries come from
+// the original-case text; characters are compared in searchText.
+func matchAcronym(text, searchText, searchQuery string, asciiPath, withPositions, withScore bool) (bool, []int, int) {
+	needPositions := withPositions || withScore
+	var (
+		ok        bool
+		positions []int
+	)
+	if asciiPath {
+		// Check first: in fuzzy mode this runs on every greedy hit, and
+		// most of them aren't acronyms.
+		if ok, _ = acronymASCII(text, searchText, searchQuery, false); ok && needPositions {
+			ok, positions = acronymASCII(text, searchText, searchQuery, true)
+		}
+	} else {
+		ok, positions = acronymRunes(text, searchText, searchQuery, needPositions)
+	}
+	if !ok || !withScore {
+		return ok, positions, 0
+	}
+	return true, positions, scoreFixed(text, asciiPath, positions)
+}
+
+// acronymASCII walks searchText for each query byte in turn, taking only
+// occurrences that start a word. Taking the first such occurrence is
+// enough: any later one leaves less text for the rest of the query.
+func acronymASCII(text, searchText, searchQuery string, withPositions bool) (bool, []int) {
+	if len(text) != len(searchText) {
+		text = searchText
+	}
+	var positions []int
+	if withPositions {
+		positions = make([]int, 0, len(searchQuery))
+	}
+	qi := 0
+	for i := 0; qi < len(searchQuery); i++ {
+		// Most items fail on the first few query bytes; IndexByte skips
+		// to the candidates far faster than comparing byte by byte.
+		next := strings.IndexByte(searchText[i:], searchQuery[qi])
+		if next < 0 {
+			break
+		}
+		i += next
+		prev := charWhite
+		if i > 0 {
+			prev = classOf(rune(text[i-1]))
+		}
+		if !isWordStart(prev, classOf(rune(text[i]))) {
+			continue
+		}
+		if withPositions {
+			positions = append(positions, i)
+		}
+		qi++
+	}
+	if qi < len(searchQuery) {
+		return false, nil
+	}
+	return true, positions
+}
+
+func acronymRunes(text, searchText, searchQuery string, withPositions bool) (bool, []int) {
+	textRunes := []rune(searchText)
+	queryRunes := []rune(searchQuery)
+	classRunes := []rune(text)
+	if len(classRunes) != len(textRunes) {
+		// Case folding changed the rune count; see fuzzyMatchV2Runes.
+		classRunes = textRunes
+	}
+	var positions []int
+	if withPositions {
+		positions = make([]int, 0, len(queryRunes))
+	}
+	qi := 0
+	for i := 0; i < len(textRunes) && qi < len(queryRunes); i++ {
+		if textRunes[i] != queryRunes[qi] {
+			continue
+		}
+		prev := charWhite
+		if i > 0 {
+			prev = classOf(classRunes[i-1])
+		}
+		if !isWordStart(prev, classOf(classRunes[i])) {
+			continue
+		}
+		if withPositions {
+			positions = append(positions, i)
+		}
+		qi++
+	}
+	if qi < len(queryRunes) {
+		return false, nil
+	}
+	return true, positions
+}
@@ -0,0 +1,68 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAcronym_MatchesWordInitials(t *testing.T) {
+	tests := []struct {
+		query, text string
+		want        []int
+	}{
+		{"gld", "goose-launcher-daemon", []int{0, 6, 15}},
+		{"fb", "FooBar.go", []int{0, 3}},
+		{"gd", "good-day", []int{0, 5}},    // not the "d" of "good"
+		{"ml2", "my-lib2", []int{0, 3, 6}}, // digits start a word
+		{"äb", "ärger-bar", []int{0, 6}},
+	}
+	for _, tt := range tests {
+		for _, exact := range []bool{false, true} {
+			m := NewFuzzyMatcherWithOptions(Options{Exact: exact})
+			ok, positions, _ := m.Match(tt.query, initItem(tt.text))
+			if !ok {
+				t.Errorf("exact=%v: %q should match %q", exact, tt.query, tt.text)
+				continue
+			}
+			if !reflect.DeepEqual(positions, tt.want) {
+				t.Errorf("exact=%v: %q in %q: positions %v, want %v", exact, tt.query, tt.text, positions, tt.want)
+			}
+		}
+	}
+}
+
+func TestAcronym_ExactMode(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Exact: true, Extended: true})
+	tests := []struct {
+		query, text string
+		want        bool
+	}{
+		{"gld", "goose-launcher-daemon", true},
+		{"gld", "golden", false},                // "l" and "d" aren't word starts
+		{"gl", "goose-launcher-daemon", true},   // a prefix of the initials
+		{"dl", "goose-launcher-daemon", false},  // out of order
+		{"launcher", "goose-launcher", true},    // substrings still match as before
+		{"'gld", "goose-launcher-daemon", true}, // ' switches to fuzzy
+		{"^gld", "goose-launcher-daemon", false},
+		{"!gld", "goose-launcher-daemon", true}, // negation stays a plain substring test
+		{"g.d", "goose-launcher-daemon", false}, // punctuation isn't an initialism
+	}
+	for _, tt := range tests {
+		if ok, _, _ := m.Match(tt.query, initItem(tt.text)); ok != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.query, tt.text, ok, tt.want)
+		}
+		if ok := m.MatchOnly(tt.query, initItem(tt.text)); ok != tt.want {
+			t.Errorf("MatchOnly(%q, %q) = %v, want %v", tt.query, tt.text, ok, tt.want)
+		}
+	}
+}
+
+func TestAcronym_OutscoresGreedyAlignment(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{})
+	greedy := m.pattern("gd").single
+	_, _, acronymScore := m.Match("gd", initItem("good-day"))
+	_, _, greedyScore := matchV1(termFuzzy, "good-day", "good-day", greedy.text, true, true, true)
+	if acronymScore <= greedyScore {
+		t.Errorf("word-initial alignment scored %d, want above the greedy %d", acronymScore, greedyScore)
+	}
+}
@@ -0,0 +1,302 @@
+package matcher
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// termKind is how a single extended-syntax term matches.
+type termKind int
+
+const (
+	termFuzzy  termKind = iota // foo   (fuzzy mode) / 'foo (exact mode)
+	termExact                  // 'foo  (fuzzy mode) / foo  (exact mode)
+	termPrefix                 // ^foo
+	termSuffix                 // foo$
+	termEqual                  // ^foo$
+)
+
+// term is one parsed token of an extended query. text is already case
+// folded unless caseSensitive is set (smart case decides per term).
+type term struct {
+	kind          termKind
+	text          string
+	negate        bool
+	caseSensitive bool
+	// typos is the edit budget for approximate matching (--typos), 0 when
+	// the term must match as typed.
+	typos int
+	// mask is input.CharMask(text): items whose Mask lacks any of its
+	// bits can't contain the term.
+	mask uint64
+	// acronym lets a plain term also match word-initial characters only
+	// ("gld" → goose-launcher-daemon).
+	acronym bool
+}
+
+// pattern is a parsed query: every group must match (AND), and a group
+// matches when any of its terms does (OR). Without --extended the whole
+// query is one term.
+type pattern struct {
+	query  string
+	groups [][]term
+	// single is set when the query is exactly one positive term, so match
+	// can skip the AND/OR loop entirely.
+	single *term
+	// re is the compiled query in --regex mode; err is set instead when
+	// the query doesn't compile.
+	re  *regexp.Regexp
+	err error
+}
+
+// pattern returns the parsed form of query, reusing the cached parse when
+// the query hasn't changed since the last call.
+func (m *FuzzyMatcher) pattern(query string) *pattern {
+	if p := m.lastPattern.Load(); p != nil && p.query == query {
+		return p
+	}
+	p := m.parsePattern(query)
+	m.lastPattern.Store(p)
+	return p
+}
+
+// parsePattern parses fzf's extended search syntax:
+//
+//	foo bar    items matching foo AND bar
+//	a | b      items matching a OR b
+//	!foo       items NOT containing foo (exact)
+//	^foo       items starting with foo
+//	foo$       items ending with foo
+//	^foo$      items equal to foo
+//	'foo       exact substring in fuzzy mode; fuzzy in exact mode
+//
+// A backslash-escaped space ("\ ") is a literal space inside a term.
+// Operators with nothing after them ("!", "^", "'") are ignored.
+func (m *FuzzyMatcher) parsePattern(query string) *pattern {
+	if m.regex {
+		return m.parseRegexPattern(query)
+	}
+	if !m.extended {
+		t := m.newTerm(m.defaultKind(), query)
+		return &pattern{query: query, groups: [][]term{{t}}, single: &t}
+	}
+
+	const escapedSpace = "\x00"
+	q := strings.ReplaceAll(query, `\ `, escapedSpace)
+
+	p := &pattern{query: query}
+	joinNext := false
+	for _, tok := range strings.Fields(q) {
+		if tok == "|" {
+			joinNext = len(p.groups) > 0
+			continue
+		}
+		t, ok := m.parseTerm(strings.ReplaceAll(tok, escapedSpace, " "))
+		if !ok {
+			joinNext = false
+			continue
+		}
+		if joinNext {
+			last := len(p.groups) - 1
+			p.groups[last] = append(p.groups[last], t)
+		} else {
+			p.groups = append(p.groups, []term{t})
+		}
+		joinNext = false
+	}
+
+	if len(p.groups) == 1 && len(p.groups[0]) == 1 && !p.groups[0][0].negate {
+		p.single = &p.groups[0][0]
+	}
+	return p
+}
+
+func (m *FuzzyMatcher) parseTerm(tok string) (term, bool) {
+	t := term{kind: m.defaultKind()}
+
+	if strings.HasPrefix(tok, "!") {
+		// Negated terms are exact by default: "not containing these chars
+		// in order" would reject almost everything.
+		t.negate = true
+		t.kind = termExact
+		tok = tok[1:]
+	}
+	if tok != "$" && strings.HasSuffix(tok, "$") {
+		t.kind = termSuffix
+		tok = tok[:len(tok)-1]
+	}
+	if strings.HasPrefix(tok, "'") {
+		if !m.exact && !t.negate {
+			t.kind = termExact
+		} else {
+			t.kind = termFuzzy
+		}
+		tok = tok[1:]
+	} else if strings.HasPrefix(tok, "^") {
+		if t.kind == termSuffix {
+			t.kind = termEqual
+		} else {
+			t.kind = termPrefix
+		}
+		tok = tok[1:]
+	}
+
+	if tok == "" {
+		return term{}, false
+	}
+	nt := m.newTerm(t.kind, tok)
+	nt.negate = t.negate
+	if nt.negate {
+		nt.typos = 0 // "!foo" excludes foo, not everything resembling it
+		nt.acronym = false
+	}
+	return nt, true
+}
+
+// Narrows reports whether next only ever matches a subset of what prev
+// matches, so a filter pass for next can start from prev's results.
+// Appending to a query narrows it — a longer term is harder to match and a
+// new term is one more AND — except where the appended text changes what
+// earlier text means: "|" adds alternatives, "!" terms match more as they
+// grow, "$" and "\" re-anchor or re-tokenize the term before them.
+// Regular expressions have no such structure.
+func (m *FuzzyMatcher) Narrows(prev, next string) bool {
+	if m.regex || prev == "" || !strings.HasPrefix(next, prev) {
+		return false
+	}
+	if m.extended && strings.ContainsAny(next, `|!$\`) {
+		return false
+	}
+	// A term's typo budget grows with its length, and a bigger budget
+	// matches more.
+	return m.typos == 0 || !typoBudgetGrows(m.parsePattern(prev), m.parsePattern(next))
+}
+
+// typoBudgetGrows reports whether any term of prev has a larger typo
+// budget in next, term for term.
+func typoBudgetGrows(prev, next *pattern) bool {
+	var budgets []int
+	for _, g := range prev.groups {
+		for _, t := range g {
+			budgets = append(budgets, t.typos)
+		}
+	}
+	i := 0
+	for _, g := range next.groups {
+		for _, t := range g {
+			if i < len(budgets) && t.typos > budgets[i] {
+				return true
+			}
+			i++
+		}
+	}
+	return false
+}
+
+// newTerm folds text for the matcher's case and normalization modes.
+func (m *FuzzyMatcher) newTerm(kind termKind, text string) term {
+	t := term{kind: kind, caseSensitive: m.caseSensitiveFor(text)}
+	switch {
+	case m.normalize:
+		t.text = input.Normalize(text, !t.caseSensitive)
+	case !t.caseSensitive:
+		t.text = strings.ToLower(text)
+	default:
+		t.text = text
+	}
+	t.mask = input.CharMask(t.text)
+	if m.typos > 0 && (kind == termFuzzy || kind == termExact) {
+		t.typos = typoBudget(m.typos, utf8.RuneCountInString(t.text))
+	}
+	t.acronym = kind == m.defaultKind() && acronymTerm(t.text)
+	return t
+}
+
+// matchPattern evaluates a multi-term pattern. Positions from every
+// positive term that matched are merged (sorted, deduplicated) so the
+// list highlights all of them; scores add up.
+func (m *FuzzyMatcher) matchPattern(p *pattern, s *subject, withPositions, withScore bool) (bool, []int, int) {
+	var positions []int
+	total := 0
+	contributors := 0
+	for _, group := range p.groups {
+		matched := false
+		for i := range group {
+			t := &group[i]
+			want := !t.negate
+			ok, pos, score := m.matchSubject(t, s, withPositions && want, withScore && want)
+			if ok != want {
+				continue
+			}
+			matched = true
+			if want {
+				if withPositions {
+					positions = append(positions, pos...)
+					contributors++
+				}
+				total += score
+			}
+			break
+		}
+		if !matched {
+			return false, nil, 0
+		}
+	}
+	if contributors > 1 {
+		positions = mergePositions(positions)
+	}
+	return true, positions, total
+}
+
+// mergePositions sorts positions and drops duplicates in place.
+func mergePositions(positions []int) []int {
+	sort.Ints(positions)
+	out := positions[:0]
+	for i, p := range positions {
+		if i > 0 && p == positions[i-1] {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// anchoredMatch handles ^prefix, suffix$ and ^equal$ terms.
+func anchoredMatch(kind termKind, text, searchText, searchQuery string, asciiPath, withPositions, withScore bool) (bool, []int, int) {
+	var ok bool
+	start := 0 // byte offset of the match in searchText
+	switch kind {
+	case termPrefix:
+		ok = strings.HasPrefix(searchText, searchQuery)
+	case termSuffix:
+		ok = strings.HasSuffix(searchText, searchQuery)
+		start = len(searchText) - len(searchQuery)
+	case termEqual:
+		ok = searchText == searchQuery
+	}
+	if !ok {
+		return false, nil, 0
+	}
+	if !withPositions && !withScore {
+		return true, nil, 0
+	}
+
+	startRune, n := start, len(searchQuery)
+	if !asciiPath {
+		startRune = utf8.RuneCountInString(searchText[:start])
+		n = utf8.RuneCountInString(searchQuery)
+	}
+	positions := make([]int, n)
+	for i := range positions {
+		positions[i] = startRune + i
+	}
+	score := 0
+	if withScore {
+		score = scoreFixed(text, asciiPath, positions)
+	}
+	return true, positions, score
+}
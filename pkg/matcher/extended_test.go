@@ -0,0 +1,155 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newExtendedMatcher(exact bool) *FuzzyMatcher {
+	return NewFuzzyMatcherWithOptions(Options{Exact: exact, Extended: true})
+}
+
+func TestExtended_Operators(t *testing.T) {
+	items := []string{
+		"src/main.go",
+		"src/main_test.go",
+		"docs/readme.md",
+		"main",
+		"cmd/tool/main.go",
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"main go", []string{"src/main.go", "src/main_test.go", "cmd/tool/main.go"}},
+		{"main !test", []string{"src/main.go", "main", "cmd/tool/main.go"}},
+		{"^src", []string{"src/main.go", "src/main_test.go"}},
+		{".md$", []string{"docs/readme.md"}},
+		{"^main$", []string{"main"}},
+		{"readme | tool", []string{"docs/readme.md", "cmd/tool/main.go"}},
+		{"'n.g", []string{"src/main.go", "cmd/tool/main.go"}},
+		{"!go !md", []string{"main"}},
+		{"^src !test | readme", []string{"src/main.go"}},
+	}
+	m := newExtendedMatcher(false)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, text := range items {
+				if ok, _, _ := m.Match(tt.query, initItem(text)); ok {
+					got = append(got, text)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("query %q matched %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// In exact mode plain terms are substrings and ' flips a term to fuzzy.
+func TestExtended_ExactModeQuoteIsFuzzy(t *testing.T) {
+	m := newExtendedMatcher(true)
+	item := initItem("goose-launcher")
+	if ok, _, _ := m.Match("gsl", item); ok {
+		t.Error("plain term should be a substring match in exact mode")
+	}
+	if ok, _, _ := m.Match("'gsl", item); !ok {
+		t.Error("'term should be fuzzy in exact mode")
+	}
+}
+
+func TestExtended_MergesPositionsFromAllTerms(t *testing.T) {
+	m := newExtendedMatcher(true)
+	ok, positions, _ := m.Match("lib !test ^go", initItem("go/lib"))
+	if !ok {
+		t.Fatal("expected match")
+	}
+	want := []int{0, 1, 3, 4, 5}
+	if !reflect.DeepEqual(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+}
+
+func TestExtended_OverlappingPositionsDeduplicated(t *testing.T) {
+	m := newExtendedMatcher(true)
+	_, positions, _ := m.Match("laun unch", initItem("launcher"))
+	want := []int{0, 1, 2, 3, 4, 5}
+	if !reflect.DeepEqual(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+}
+
+func TestExtended_SuffixPositionsNonASCII(t *testing.T) {
+	m := newExtendedMatcher(true)
+	ok, positions, _ := m.Match("fé$", initItem("café"))
+	if !ok {
+		t.Fatal("expected match")
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+}
+
+func TestExtended_EscapedSpace(t *testing.T) {
+	m := newExtendedMatcher(true)
+	if ok, _, _ := m.Match(`foo\ bar`, initItem("foo bar")); !ok {
+		t.Error("escaped space should match a literal space")
+	}
+	if ok, _, _ := m.Match(`foo\ bar`, initItem("bar foo")); ok {
+		t.Error("escaped space should keep the term together")
+	}
+}
+
+func TestExtended_BareOperatorsMatchEverything(t *testing.T) {
+	m := newExtendedMatcher(false)
+	for _, q := range []string{"!", "^", "'", "|", "  "} {
+		if ok, _, _ := m.Match(q, initItem("anything")); !ok {
+			t.Errorf("query %q should match everything", q)
+		}
+	}
+}
+
+// Without Extended the whole query stays one literal term.
+func TestExtended_DisabledKeepsLiteralQuery(t *testing.T) {
+	m := NewFuzzyMatcher(false, true)
+	if ok, _, _ := m.Match("main !test", initItem("src/main.go")); ok {
+		t.Error("extended syntax should be inert when disabled")
+	}
+	// The operators are plain characters: spaces, '!', '|', '^' and '$'
+	// all have to appear in the item.
+	for _, q := range []string{"main !test", "a | b", "^foo$", "'quoted"} {
+		if ok, _, _ := m.Match(q, initItem("x "+q+" y")); !ok {
+			t.Errorf("Match(%q) = false, want a literal match", q)
+		}
+	}
+}
+
+func TestNarrows(t *testing.T) {
+	tests := []struct {
+		prev, next string
+		extended   bool
+		want       bool
+	}{
+		{"han", "hand", false, true},
+		{"han", "hand", true, true},
+		{"han", "han dl", true, true},
+		{"han", "ha", false, false},
+		{"han", "hxn", false, false},
+		{"", "h", false, false},
+		{"main", "main | go", true, false},
+		{"main !t", "main !te", true, false},
+		{"main", "main$", true, false},
+		{`main`, `main\ go`, true, false},
+		{"main", "main | go", false, true}, // plain mode: "|" is literal
+	}
+	for _, tt := range tests {
+		m := NewFuzzyMatcherWithOptions(Options{Extended: tt.extended})
+		if got := Narrows(m, tt.prev, tt.next); got != tt.want {
+			t.Errorf("extended=%v Narrows(%q, %q) = %v, want %v", tt.extended, tt.prev, tt.next, got, tt.want)
+		}
+	}
+	if Narrows(newRegexMatcher(), "a", "ab") {
+		t.Error("regex queries never narrow")
+	}
+}
@@ -1,7 +1,11 @@
 package matcher
 
 import (
+	"fmt"
+	"math/bits"
 	"strings"
+	"sync/atomic"
+	"unicode"
 
 	"github.com/sam33r/goose-launcher/pkg/input"
 )
@@ -14,34 +18,196 @@ import (
 //   - When both query and text are ASCII we operate on bytes directly and
 //     skip []rune conversion entirely (the dominant alloc on large inputs).
 type FuzzyMatcher struct {
-	caseSensitive bool
-	exact         bool
+	caseMode  CaseMode
+	exact     bool
+	algo      Algo
+	extended  bool
+	normalize bool
+	regex     bool
+	typos     int
+
+	// regexes caches compiled --regex queries across filter passes.
+	regexes regexCache
+
+	// lastPattern caches the parsed form of the most recent extended query.
+	// Every item in a filter pass sees the same query, so one slot is
+	// enough; atomic so concurrent filter workers can share a matcher.
+	lastPattern atomic.Pointer[pattern]
+}
+
+// CaseMode controls case sensitivity.
+type CaseMode int
+
+const (
+	// CaseIgnore always folds case (-i).
+	CaseIgnore CaseMode = iota
+	// CaseRespect never folds case (+i).
+	CaseRespect
+	// CaseSmart folds case unless the query (or, in extended mode, the
+	// term) contains an uppercase letter — typing "Foo" means you care.
+	CaseSmart
+)
+
+// ParseCaseMode maps the config value ("smart", "ignore", "respect") to a
+// CaseMode.
+func ParseCaseMode(s string) (CaseMode, error) {
+	switch s {
+	case "smart":
+		return CaseSmart, nil
+	case "", "ignore":
+		return CaseIgnore, nil
+	case "respect":
+		return CaseRespect, nil
+	default:
+		return CaseIgnore, fmt.Errorf("unsupported case mode %q (want smart, ignore or respect)", s)
+	}
+}
+
+// Options configures a FuzzyMatcher. The zero value is case-insensitive
+// v1 fuzzy matching.
+type Options struct {
+	Case  CaseMode
+	Exact bool
+	Algo  Algo // Fuzzy alignment strategy; ignored in exact mode
+	// Extended enables fzf's extended search syntax in the query: space
+	// separated AND terms, "|" OR, and the !, ^, $ and ' operators.
+	Extended bool
+	// Normalize matches against input.Item.Norm so diacritics, compatibility
+	// forms and full case folding don't get in the way ("cafe" finds
+	// "Café"). Disabled by --literal.
+	Normalize bool
+	// Regex treats the whole query as a Go regular expression matched
+	// against the original item text. Overrides Exact, Algo and Extended.
+	Regex bool
+	// Typos (0-2) lets plain terms match items within that many edits
+	// (Damerau-Levenshtein) when they don't match as typed. Approximate
+	// hits score below every exact one.
+	Typos int
 }
 
 // NewFuzzyMatcher creates a new fuzzy matcher
 func NewFuzzyMatcher(caseSensitive, exact bool) *FuzzyMatcher {
+	caseMode := CaseIgnore
+	if caseSensitive {
+		caseMode = CaseRespect
+	}
+	return NewFuzzyMatcherWithOptions(Options{
+		Case:  caseMode,
+		Exact: exact,
+	})
+}
+
+// NewFuzzyMatcherWithOptions creates a matcher from the full option set.
+// The daemon builds one per request from the parsed flags.
+func NewFuzzyMatcherWithOptions(opts Options) *FuzzyMatcher {
 	return &FuzzyMatcher{
-		caseSensitive: caseSensitive,
-		exact:         exact,
+		caseMode:  opts.Case,
+		exact:     opts.Exact,
+		algo:      opts.Algo,
+		extended:  opts.Extended,
+		normalize: opts.Normalize,
+		regex:     opts.Regex,
+		typos:     opts.Typos,
+	}
+}
+
+// Options returns the options the matcher was built with, so callers can
+// derive a variant (e.g. the UI's regex toggle) without tracking them.
+func (m *FuzzyMatcher) Options() Options {
+	return Options{
+		Case:      m.caseMode,
+		Exact:     m.exact,
+		Algo:      m.algo,
+		Extended:  m.extended,
+		Normalize: m.normalize,
+		Regex:     m.regex,
+		Typos:     m.typos,
+	}
+}
+
+// QueryError reports why query can't be matched (an invalid --regex
+// pattern); nil when it is usable. Matching an invalid query matches
+// nothing, so the UI checks this first and keeps its previous results.
+func (m *FuzzyMatcher) QueryError(query string) error {
+	if query == "" {
+		return nil
 	}
+	return m.pattern(query).err
+}
+
+// caseSensitiveFor reports whether q should be matched without case
+// folding under the matcher's case mode.
+func (m *FuzzyMatcher) caseSensitiveFor(q string) bool {
+	switch m.caseMode {
+	case CaseRespect:
+		return true
+	case CaseSmart:
+		return hasUpper(q)
+	default:
+		return false
+	}
+}
+
+// hasUpper reports whether s contains an uppercase letter. Allocation-free;
+// ASCII queries never touch the unicode tables.
+func hasUpper(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' {
+			return true
+		}
+		if c >= 0x80 {
+			for _, r := range s[i:] {
+				if unicode.IsUpper(r) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
 }
 
 // Match checks if query matches the item's text and returns match positions
-// (rune indices into item.Text) for highlighting.
-func (m *FuzzyMatcher) Match(query string, item input.Item) (bool, []int) {
-	return m.match(query, item, true)
+// (rune indices into item.Text) for highlighting, plus the alignment score
+// (higher is better). With AlgoV2 the positions are the ones that maximize
+// the score; with AlgoV1 and exact mode the fixed alignment is scored with
+// the same bonus model so scores are comparable across modes.
+func (m *FuzzyMatcher) Match(query string, item input.Item) (bool, []int, int) {
+	return m.match(query, item, true, true)
+}
+
+// MatchPositions is Match for callers that highlight but don't rank: the
+// alignment without its score. (v2 still scores, to pick the positions.)
+func (m *FuzzyMatcher) MatchPositions(query string, item input.Item) (bool, []int) {
+	ok, positions, _ := m.match(query, item, true, false)
+	return ok, positions
 }
 
 // MatchOnly is a position-free fast path for callers (e.g. counting) that
 // don't need highlight/rank positions. Saves the positions allocation.
 func (m *FuzzyMatcher) MatchOnly(query string, item input.Item) bool {
-	ok, _ := m.match(query, item, false)
+	ok, _, _ := m.match(query, item, false, false)
 	return ok
 }
 
-func (m *FuzzyMatcher) match(query string, item input.Item, withPositions bool) (bool, []int) {
+func (m *FuzzyMatcher) match(query string, item input.Item, withPositions, withScore bool) (bool, []int, int) {
 	if query == "" {
-		return true, nil
+		return true, nil, 0
+	}
+
+	if s := item.Search; s != nil {
+		// --nth: match only the selected fields, then move the positions
+		// onto the displayed text.
+		subj := subject{text: s.Text, lowerText: s.LowerText, ascii: s.ASCII, mask: item.Mask}
+		if m.normalize {
+			subj.norm = s.Norm
+		}
+		ok, positions, score := m.matchText(query, &subj, withPositions, withScore)
+		if ok && len(positions) > 0 {
+			positions = s.ToItem(positions)
+		}
+		return ok, positions, score
 	}
 
 	text, lowerText := item.Text, item.LowerText
@@ -50,29 +216,177 @@ func (m *FuzzyMatcher) match(query string, item input.Item, withPositions bool)
 		// Item built without Init() (e.g. legacy callers / hand-rolled tests).
 		// Fall back to the cold path so behavior stays correct.
 		ascii = isASCII(text) && isASCII(query)
-		if !m.caseSensitive {
-			lowerText = strings.ToLower(text)
-		} else {
-			lowerText = text
+		lowerText = strings.ToLower(text)
+	}
+	subj := subject{text: text, lowerText: lowerText, ascii: ascii, mask: item.Mask}
+	if m.normalize {
+		subj.norm = item.Norm
+	}
+	return m.matchText(query, &subj, withPositions, withScore)
+}
+
+// matchText matches query against one subject: the item's text, or its
+// --nth fields.
+func (m *FuzzyMatcher) matchText(query string, subj *subject, withPositions, withScore bool) (bool, []int, int) {
+	p := m.pattern(query)
+	if m.regex {
+		if p.re == nil {
+			return false, nil, 0
+		}
+		return matchRegex(p.re, subj.text, subj.ascii, withPositions, withScore)
+	}
+	if t := p.single; t != nil {
+		// One plain term — the common case (and the only one without
+		// --extended). Skip the AND/OR bookkeeping.
+		return m.matchSubject(t, subj, withPositions, withScore)
+	}
+	return m.matchPattern(p, subj, withPositions, withScore)
+}
+
+// subject is the per-item text a term is matched against.
+type subject struct {
+	text, lowerText string
+	ascii           bool
+	norm            *input.Normalized // nil unless normalizing and the item has a distinct normalized form
+	mask            uint64            // input.Item.Mask (covering the --nth fields too); 0 when unknown
+}
+
+// matchSubject matches one parsed term against an item, picking the
+// literal or normalized search text for the term's case mode and mapping
+// normalized positions back to rune indices in item.Text.
+func (m *FuzzyMatcher) matchSubject(t *term, s *subject, withPositions, withScore bool) (bool, []int, int) {
+	if missing := t.mask &^ s.mask; s.mask != 0 && missing != 0 {
+		// The item lacks some of the term's characters: no search can
+		// find it, and each missing one is an edit for --typos.
+		if bits.OnesCount64(missing) > t.typos {
+			return false, nil, 0
+		}
+	}
+	n := s.norm
+	if n == nil {
+		searchText := s.lowerText
+		if t.caseSensitive {
+			searchText = s.text
 		}
+		return m.matchTermApprox(t, s.text, searchText, s.ascii, withPositions, withScore)
 	}
 
-	searchText := text
-	searchQuery := query
-	if !m.caseSensitive {
-		searchText = lowerText
-		searchQuery = strings.ToLower(query)
+	searchText, posMap := n.Lower, n.LowerMap
+	if t.caseSensitive {
+		searchText, posMap = n.Text, n.TextMap
 	}
+	ok, positions, score := m.matchTermApprox(t, n.Text, searchText, n.ASCII, withPositions, withScore)
+	if ok && withPositions && posMap != nil {
+		positions = mapPositions(positions, posMap)
+	}
+	return ok, positions, score
+}
 
+// matchTermApprox is matchTerm plus the fallbacks for plain terms: the
+// word initials when the term reads as an acronym, then approximate
+// matching for terms with a typo budget.
+func (m *FuzzyMatcher) matchTermApprox(t *term, text, searchText string, ascii, withPositions, withScore bool) (bool, []int, int) {
+	ok, positions, score := m.matchTerm(t.kind, text, searchText, t.text, ascii, withPositions, withScore)
+	if t.acronym {
+		asciiPath := ascii && isASCII(t.text)
+		switch {
+		case ok && t.kind == termFuzzy && m.algo == AlgoV1 && (withPositions || withScore):
+			// The greedy walk found the characters, but maybe not at the
+			// word starts the user meant. (v2's alignment already
+			// prefers them.)
+			if aok, apos, ascore := matchAcronym(text, searchText, t.text, asciiPath, withPositions, withScore); aok {
+				return aok, apos, ascore
+			}
+		case !ok && t.kind == termExact:
+			if aok, apos, ascore := matchAcronym(text, searchText, t.text, asciiPath, withPositions, withScore); aok {
+				return aok, apos, ascore
+			}
+		}
+	}
+	if ok || t.typos == 0 {
+		return ok, positions, score
+	}
+	return matchApprox(t, text, searchText, ascii && isASCII(t.text), withPositions, withScore)
+}
+
+// mapPositions rewrites normalized rune indices into item.Text rune
+// indices. Several normalized runes can come from one original rune (ß →
+// "ss"), so adjacent duplicates collapse.
+func mapPositions(positions []int, posMap []int32) []int {
+	out := positions[:0]
+	for _, p := range positions {
+		if p < 0 || p >= len(posMap) {
+			continue
+		}
+		orig := int(posMap[p])
+		if len(out) > 0 && out[len(out)-1] == orig {
+			continue
+		}
+		out = append(out, orig)
+	}
+	return out
+}
+
+// defaultKind is how a term without any operator prefix/suffix matches.
+func (m *FuzzyMatcher) defaultKind() termKind {
 	if m.exact {
-		return exactMatch(searchText, searchQuery, withPositions)
+		return termExact
 	}
+	return termFuzzy
+}
+
+// matchTerm matches one already case-folded term against searchText.
+func (m *FuzzyMatcher) matchTerm(kind termKind, text, searchText, searchQuery string, ascii, withPositions, withScore bool) (bool, []int, int) {
+	asciiPath := ascii && isASCII(searchQuery)
+
+	switch kind {
+	case termPrefix, termSuffix, termEqual:
+		return anchoredMatch(kind, text, searchText, searchQuery, asciiPath, withPositions, withScore)
+	case termFuzzy:
+		if m.algo == AlgoV2 {
+			// v2 always needs the DP to pick positions; without positions
+			// the greedy check answers "does it match" just as well.
+			if !withPositions && !withScore {
+				return matchV1(termFuzzy, text, searchText, searchQuery, asciiPath, false, false)
+			}
+			if asciiPath {
+				return fuzzyMatchV2ASCII(text, searchText, searchQuery, withPositions)
+			}
+			return fuzzyMatchV2Runes(text, searchText, searchQuery, withPositions)
+		}
+	}
+	return matchV1(kind, text, searchText, searchQuery, asciiPath, withPositions, withScore)
+}
+
+// matchV1 covers exact substring terms and the greedy fuzzy algorithm.
+// Scoring needs positions, so withScore implies computing them.
+func matchV1(kind termKind, text, searchText, searchQuery string, asciiPath, withPositions, withScore bool) (bool, []int, int) {
+	var (
+		ok        bool
+		positions []int
+	)
+	needPositions := withPositions || withScore
+	switch {
+	case kind == termExact:
+		ok, positions = exactMatch(searchText, searchQuery, needPositions)
+	case asciiPath:
+		// ASCII byte-level fast path: positions are byte == rune indices.
+		ok, positions = fuzzyMatchASCII(searchText, searchQuery, needPositions)
+	default:
+		ok, positions = fuzzyMatchRunes(searchText, searchQuery, needPositions)
+	}
+	if !ok || !withScore {
+		return ok, positions, 0
+	}
+	return true, positions, scoreFixed(text, asciiPath, positions)
+}
 
-	// ASCII byte-level fast path: positions are byte == rune indices.
-	if ascii && isASCII(searchQuery) {
-		return fuzzyMatchASCII(searchText, searchQuery, withPositions)
+// scoreFixed scores a fixed alignment against the original-case text.
+func scoreFixed(text string, asciiPath bool, positions []int) int {
+	if asciiPath && isASCII(text) {
+		return scorePositionsASCII(text, positions)
 	}
-	return fuzzyMatchRunes(searchText, searchQuery, withPositions)
+	return scorePositionsRunes(text, positions)
 }
 
 // fuzzyMatchASCII walks the bytes of searchText looking for each byte of
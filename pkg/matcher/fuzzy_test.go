@@ -1,6 +1,7 @@
 package matcher
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/sam33r/goose-launcher/pkg/input"
@@ -12,7 +13,7 @@ func TestFuzzyMatch_SimpleMatch(t *testing.T) {
 	item := input.Item{Text: "Downloads/file.txt", Index: 0}
 	query := "dwn"
 
-	match, positions := matcher.Match(query, item)
+	match, positions, _ := matcher.Match(query, item)
 
 	if !match {
 		t.Error("expected 'dwn' to match 'Downloads/file.txt'")
@@ -37,7 +38,7 @@ func TestFuzzyMatch_NoMatch(t *testing.T) {
 	item := input.Item{Text: "Downloads/file.txt", Index: 0}
 	query := "xyz"
 
-	match, _ := matcher.Match(query, item)
+	match, _, _ := matcher.Match(query, item)
 
 	if match {
 		t.Error("expected 'xyz' not to match 'Downloads/file.txt'")
@@ -50,7 +51,7 @@ func TestFuzzyMatch_EmptyQuery(t *testing.T) {
 	item := input.Item{Text: "anything", Index: 0}
 	query := ""
 
-	match, positions := matcher.Match(query, item)
+	match, positions, _ := matcher.Match(query, item)
 
 	if !match {
 		t.Error("expected empty query to match any item")
@@ -67,7 +68,7 @@ func TestExactMatch(t *testing.T) {
 	item := input.Item{Text: "Documents/notes.txt", Index: 0}
 	query := "notes"
 
-	match, positions := matcher.Match(query, item)
+	match, positions, _ := matcher.Match(query, item)
 
 	if !match {
 		t.Error("expected 'notes' to match 'Documents/notes.txt' in exact mode")
@@ -84,14 +85,210 @@ func TestCaseSensitiveMatch(t *testing.T) {
 	item := input.Item{Text: "Downloads", Index: 0}
 
 	// Should match with correct case
-	match1, _ := matcher.Match("Down", item)
+	match1, _, _ := matcher.Match("Down", item)
 	if !match1 {
 		t.Error("expected 'Down' to match 'Downloads' (case-sensitive)")
 	}
 
 	// Should NOT match with wrong case
-	match2, _ := matcher.Match("down", item)
+	match2, _, _ := matcher.Match("down", item)
 	if match2 {
 		t.Error("expected 'down' NOT to match 'Downloads' (case-sensitive)")
 	}
 }
+
+func TestSmartCase(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart})
+	item := input.Item{Text: "Downloads/readme", Index: 0}
+	item.Init()
+
+	if ok, _, _ := m.Match("down", item); !ok {
+		t.Error("lowercase query should match case-insensitively")
+	}
+	if ok, _, _ := m.Match("Down", item); !ok {
+		t.Error("'Down' should match 'Downloads' case-sensitively")
+	}
+	if ok, _, _ := m.Match("ReadMe", item); ok {
+		t.Error("'ReadMe' should not match 'readme' once the query has uppercase")
+	}
+}
+
+func TestSmartCase_NonASCII(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart})
+	item := input.Item{Text: "Ärger/übersicht", Index: 0}
+	item.Init()
+
+	if ok, _, _ := m.Match("är", item); !ok {
+		t.Error("lowercase non-ASCII query should fold case")
+	}
+	if ok, _, _ := m.Match("Üb", item); ok {
+		t.Error("uppercase non-ASCII query should respect case")
+	}
+}
+
+// In extended mode smart case is decided per term, like fzf.
+func TestSmartCase_PerExtendedTerm(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart, Exact: true, Extended: true})
+	item := input.Item{Text: "FooBar.go", Index: 0}
+	item.Init()
+
+	if ok, _, _ := m.Match("Bar foo", item); !ok {
+		t.Error("expected 'Bar' (sensitive) and 'foo' (folded) both to match")
+	}
+	if ok, _, _ := m.Match("BAR foo", item); ok {
+		t.Error("expected 'BAR' to fail case-sensitively")
+	}
+}
+
+func TestSmartCase_LowercaseQueryDoesNotAllocate(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart})
+	item := input.Item{Text: "internal/pkg/Handler.go", Index: 0}
+	item.Init()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m.MatchOnly("handler", item)
+	})
+	if allocs != 0 {
+		t.Errorf("MatchOnly allocated %.0f times per call, want 0", allocs)
+	}
+}
+
+func TestParseCaseMode(t *testing.T) {
+	for in, want := range map[string]CaseMode{"smart": CaseSmart, "ignore": CaseIgnore, "respect": CaseRespect} {
+		got, err := ParseCaseMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseCaseMode(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseCaseMode("upper"); err == nil {
+		t.Error("expected error for unknown case mode")
+	}
+}
+
+func TestNormalizedMatch(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart, Exact: true, Normalize: true})
+	tests := []struct {
+		query string
+		text  string
+		want  []int
+	}{
+		{"cafe", "Le Café.txt", []int{3, 4, 5, 6}},
+		{"Cafe", "Le Café.txt", []int{3, 4, 5, 6}},
+		{"naive", "naïve", []int{0, 1, 2, 3, 4}},
+		{"strasse", "Hauptstraße 1", []int{5, 6, 7, 8, 9, 10}},
+		{"straße", "strasse", []int{0, 1, 2, 3, 4, 5, 6}},
+		{"foo", "ＦＯＯ.txt", []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		item := input.Item{Text: tt.text}
+		item.Init()
+		ok, positions, _ := m.Match(tt.query, item)
+		if !ok {
+			t.Errorf("%q should match %q", tt.query, tt.text)
+			continue
+		}
+		if len(positions) != len(tt.want) {
+			t.Errorf("%q on %q: positions = %v, want %v", tt.query, tt.text, positions, tt.want)
+			continue
+		}
+		for i := range tt.want {
+			if positions[i] != tt.want[i] {
+				t.Errorf("%q on %q: positions = %v, want %v", tt.query, tt.text, positions, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// --nth limits matching to some fields; positions land on the displayed
+// text, across every matching mode.
+func TestMatch_NthFields(t *testing.T) {
+	opts := input.ParseOptions{Delimiter: input.ParseDelimiter(":"), Nth: []input.FieldRange{{From: 2, To: 2}}}
+	item := input.ParseLineWithOptions("main:café.go:12", 0, opts)
+
+	matchers := map[string]Matcher{
+		"exact": NewFuzzyMatcherWithOptions(Options{Exact: true, Normalize: true}),
+		"fuzzy": NewFuzzyMatcherWithOptions(Options{Normalize: true}),
+		"v2":    NewFuzzyMatcherWithOptions(Options{Algo: AlgoV2, Normalize: true}),
+		"regex": NewFuzzyMatcherWithOptions(Options{Regex: true}),
+	}
+	for name, m := range matchers {
+		query := "cafe"
+		if name == "regex" {
+			query = "caf."
+		}
+		ok, positions, _ := m.Match(query, item)
+		if !ok || !reflect.DeepEqual(positions, []int{5, 6, 7, 8}) {
+			t.Errorf("%s: Match(%q) = %v %v, want positions [5 6 7 8]", name, query, ok, positions)
+		}
+		if ok, _, _ := m.Match("main", item); ok {
+			t.Errorf("%s: field 1 is outside --nth and must not match", name)
+		}
+	}
+}
+
+func TestNormalizedMatch_LiteralOptOut(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart, Exact: true})
+	item := input.Item{Text: "Café"}
+	item.Init()
+
+	if ok, _, _ := m.Match("cafe", item); ok {
+		t.Error("without Normalize, 'cafe' should not match 'Café'")
+	}
+	if ok, _, _ := m.Match("café", item); !ok {
+		t.Error("without Normalize, the literal query should still match")
+	}
+}
+
+// The Mask prefilter only skips work: every query must get the same
+// answer as on an item without a mask.
+func TestMatch_MaskPrefilterAgrees(t *testing.T) {
+	texts := []string{"goose-launcher", "Café Crème", "Straße", "FooBar.go", "lib/ünïcode.txt", "main_test.go"}
+	queries := []string{"launch", "lanucher", "gl", "cafe", "CAFÉ", "strasse", "fb", "unicode", "xyz", "main !test", "^foo", "go$", "zz | main"}
+	for _, opts := range []Options{
+		{Case: CaseSmart, Exact: true, Extended: true, Normalize: true},
+		{Case: CaseSmart, Extended: true, Normalize: true, Typos: 2},
+		{Case: CaseRespect, Exact: true, Extended: true},
+		{Algo: AlgoV2, Extended: true},
+	} {
+		m := NewFuzzyMatcherWithOptions(opts)
+		for _, text := range texts {
+			masked := initItem(text)
+			unmasked := masked
+			unmasked.Mask = 0
+			for _, q := range queries {
+				ok1, pos1, score1 := m.Match(q, masked)
+				ok2, pos2, score2 := m.Match(q, unmasked)
+				if ok1 != ok2 || !reflect.DeepEqual(pos1, pos2) || score1 != score2 {
+					t.Errorf("%+v: %q in %q: masked (%v %v %d) != unmasked (%v %v %d)", opts, q, text, ok1, pos1, score1, ok2, pos2, score2)
+				}
+			}
+		}
+	}
+}
+
+// MatchPositions finds Match's positions without scoring them.
+func TestMatchPositions_SkipsScore(t *testing.T) {
+	items := []input.Item{initItem("internal/pkg/Handler.go"), initItem("Café/crème_brûlée.txt")}
+	for _, m := range []*FuzzyMatcher{NewFuzzyMatcher(false, false), NewFuzzyMatcher(false, true), newV2Matcher()} {
+		for _, item := range items {
+			for _, query := range []string{"handler", "hgo", "crème", "cb"} {
+				ok, positions, _ := m.Match(query, item)
+				pok, ppositions := m.MatchPositions(query, item)
+				if ok != pok || !reflect.DeepEqual(positions, ppositions) {
+					t.Errorf("%q on %q: MatchPositions = %v %v, Match = %v %v", query, item.Text, pok, ppositions, ok, positions)
+				}
+			}
+		}
+	}
+
+	// Scoring a non-ASCII text reads it in place.
+	item := initItem("Café/crème_brûlée.txt")
+	m := NewFuzzyMatcher(false, true)
+	allocs := testing.AllocsPerRun(100, func() {
+		m.Match("brûlée", item)
+	})
+	if allocs > 1 {
+		t.Errorf("Match allocated %.0f times per call, want only the positions", allocs)
+	}
+}
@@ -0,0 +1,472 @@
+package matcher
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// Algo selects the fuzzy alignment strategy. Exact mode ignores it — a
+// substring has exactly one leftmost alignment.
+type Algo int
+
+const (
+	// AlgoV1 takes the first greedy left-to-right alignment. Cheapest; the
+	// highlighted characters can be far from where a human would put them
+	// ("gl" against "go/lib/goose-launcher" lights up "g" and "l" of "go/lib").
+	AlgoV1 Algo = iota
+	// AlgoV2 finds the highest-scoring alignment (fzf-v2 / Smith-Waterman
+	// style) using the bonus model below. O(len(query) × window) per match,
+	// but only paid by items that already passed the greedy prefilter.
+	AlgoV2
+)
+
+// ParseAlgo maps the --algo flag value to an Algo.
+func ParseAlgo(s string) (Algo, error) {
+	switch s {
+	case "", "v1":
+		return AlgoV1, nil
+	case "v2":
+		return AlgoV2, nil
+	default:
+		return AlgoV1, fmt.Errorf("unsupported algo %q (want \"v1\" or \"v2\")", s)
+	}
+}
+
+// Scoring constants. Values follow fzf so scores feel familiar to anyone
+// tuning against it: a matched char is worth 16, a gap costs 3 to open and
+// 1 per extra char, and boundary bonuses sit around half a match.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	// Word start after a non-word char ("-foo", "_foo").
+	bonusBoundary = scoreMatch / 2
+	// Word start after whitespace (or at the start of the text) — strongest.
+	bonusBoundaryWhite = bonusBoundary + 2
+	// Word start after a path/field delimiter ("/foo", ":foo").
+	bonusBoundaryDelimiter = bonusBoundary + 1
+	// Matching a non-word char itself is rare and deliberate.
+	bonusNonWord = scoreMatch / 2
+	// camelCase hump or letter→digit transition.
+	bonusCamel123 = bonusBoundary + scoreGapExtension
+	// Minimum per-char bonus inside a consecutive run; sized so a run always
+	// beats opening a gap.
+	bonusConsecutive = -(scoreGapStart + scoreGapExtension)
+	// The first query char's bonus counts double: where a match starts is
+	// the strongest signal of intent.
+	bonusFirstCharMultiplier = 2
+)
+
+type charClass uint8
+
+const (
+	charWhite charClass = iota
+	charNonWord
+	charDelimiter
+	charLower
+	charUpper
+	charLetter // non-ASCII letter without case information
+	charNumber
+)
+
+func classOf(r rune) charClass {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return charLower
+	case r >= 'A' && r <= 'Z':
+		return charUpper
+	case r >= '0' && r <= '9':
+		return charNumber
+	case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+		return charWhite
+	case r == '/' || r == '\\' || r == ',' || r == ':' || r == ';' || r == '|':
+		return charDelimiter
+	case r >= 0x80:
+		return charLetter
+	default:
+		return charNonWord
+	}
+}
+
+// bonusFor scores the position of a char with class cur that follows a char
+// with class prev. Text start behaves as if preceded by whitespace.
+func bonusFor(prev, cur charClass) int32 {
+	if cur > charDelimiter {
+		switch prev {
+		case charWhite:
+			return bonusBoundaryWhite
+		case charDelimiter:
+			return bonusBoundaryDelimiter
+		case charNonWord:
+			return bonusBoundary
+		}
+	}
+	if prev == charLower && cur == charUpper || prev != charNumber && cur == charNumber {
+		return bonusCamel123
+	}
+	switch cur {
+	case charNonWord, charDelimiter:
+		return bonusNonWord
+	case charWhite:
+		return bonusBoundaryWhite
+	}
+	return 0
+}
+
+// bonusesASCII fills out[k] with the bonus for text[lo+k]. Classes come from
+// the original-case text so camelCase humps survive lowercasing. out must
+// hold hi-lo bonuses.
+func bonusesASCII(out []int32, text string, lo, hi int) []int32 {
+	out = out[:hi-lo]
+	prev := charWhite
+	if lo > 0 {
+		prev = classOf(rune(text[lo-1]))
+	}
+	for k := range out {
+		cur := classOf(rune(text[lo+k]))
+		out[k] = bonusFor(prev, cur)
+		prev = cur
+	}
+	return out
+}
+
+func bonusesRunes(out []int32, text []rune, lo, hi int) []int32 {
+	out = out[:hi-lo]
+	prev := charWhite
+	if lo > 0 {
+		prev = classOf(text[lo-1])
+	}
+	for k := range out {
+		cur := classOf(text[lo+k])
+		out[k] = bonusFor(prev, cur)
+		prev = cur
+	}
+	return out
+}
+
+// fuzzyMatchV2ASCII is the byte-level v2 path. Both inputs must be pure
+// ASCII; text is the original-case text (for char classes), searchText the
+// case-folded text actually compared against searchQuery.
+func fuzzyMatchV2ASCII(text, searchText, searchQuery string, withPositions bool) (bool, []int, int) {
+	lo, hi, ok := matchWindowASCII(searchText, searchQuery)
+	if !ok {
+		return false, nil, 0
+	}
+	if (hi-lo)*len(searchQuery) > maxV2Cells {
+		return matchV1(termFuzzy, text, searchText, searchQuery, true, withPositions, true)
+	}
+	slab := v2Slabs.Get().(*v2Slab)
+	defer v2Slabs.Put(slab)
+	slab.grow(hi-lo, len(searchQuery))
+	bonus := bonusesASCII(slab.bonus, text, lo, hi)
+	slab.text = append(slab.text[:0], searchText[lo:hi]...)
+	slab.query = append(slab.query[:0], searchQuery...)
+	positions, score := alignV2(slab, slab.text, slab.query, bonus, withPositions)
+	for i := range positions {
+		positions[i] += lo
+	}
+	return true, positions, score
+}
+
+// fuzzyMatchV2Runes is the cold path for non-ASCII inputs. Positions are
+// rune indices.
+func fuzzyMatchV2Runes(text, searchText, searchQuery string, withPositions bool) (bool, []int, int) {
+	textRunes := []rune(searchText)
+	queryRunes := []rune(searchQuery)
+	lo, hi, ok := matchWindowRunes(textRunes, queryRunes)
+	if !ok {
+		return false, nil, 0
+	}
+	if (hi-lo)*len(queryRunes) > maxV2Cells {
+		return matchV1(termFuzzy, text, searchText, searchQuery, false, withPositions, true)
+	}
+	slab := v2Slabs.Get().(*v2Slab)
+	defer v2Slabs.Put(slab)
+	slab.grow(hi-lo, len(queryRunes))
+	classRunes := []rune(text)
+	if len(classRunes) != len(textRunes) {
+		// Case folding changed the rune count (rare: e.g. 'İ'). Classes
+		// from the folded text are still a reasonable approximation.
+		classRunes = textRunes
+	}
+	bonus := bonusesRunes(slab.bonus, classRunes, lo, hi)
+	positions, score := alignV2(slab, textRunes[lo:hi], queryRunes, bonus, withPositions)
+	for i := range positions {
+		positions[i] += lo
+	}
+	return true, positions, score
+}
+
+// matchWindowASCII narrows the DP to [lo, hi): from the first occurrence of
+// the first query char to the last occurrence of the last query char, after
+// confirming a greedy match exists at all. Non-matching items (the vast
+// majority on a selective query) exit here without touching the DP.
+func matchWindowASCII(text, query string) (lo, hi int, ok bool) {
+	lo = -1
+	ti := 0
+	for qi := 0; qi < len(query); qi++ {
+		for ti < len(text) && text[ti] != query[qi] {
+			ti++
+		}
+		if ti == len(text) {
+			return 0, 0, false
+		}
+		if qi == 0 {
+			lo = ti
+		}
+		ti++
+	}
+	last := query[len(query)-1]
+	hi = len(text)
+	for hi > ti && text[hi-1] != last {
+		hi--
+	}
+	return lo, hi, true
+}
+
+func matchWindowRunes(text, query []rune) (lo, hi int, ok bool) {
+	lo = -1
+	ti := 0
+	for qi := 0; qi < len(query); qi++ {
+		for ti < len(text) && text[ti] != query[qi] {
+			ti++
+		}
+		if ti == len(text) {
+			return 0, 0, false
+		}
+		if qi == 0 {
+			lo = ti
+		}
+		ti++
+	}
+	last := query[len(query)-1]
+	hi = len(text)
+	for hi > ti && text[hi-1] != last {
+		hi--
+	}
+	return lo, hi, true
+}
+
+// maxV2Cells caps the matrix alignV2 fills, window × len(query) cells, as
+// fzf's slab does. One long line (minified JS, a log line) against a long
+// query would otherwise cost megabytes per item; past the cap the item
+// gets the v1 greedy alignment, whose cost doesn't grow with the product.
+const maxV2Cells = 100 * 1024
+
+// v2Slab holds alignV2's buffers between calls, so matching item after
+// item doesn't allocate them each time. Each filter shard worker takes its
+// own from v2Slabs; at most maxV2Cells cells, the buffers stay small.
+type v2Slab struct {
+	H, C        []int32
+	bonus       []int32
+	text, query []byte // the ASCII path's window and query
+}
+
+var v2Slabs = sync.Pool{New: func() any { return new(v2Slab) }}
+
+// grow makes room for an n × m alignment.
+func (s *v2Slab) grow(n, m int) {
+	if cap(s.H) < n*m {
+		s.H = make([]int32, n*m)
+		s.C = make([]int32, n*m)
+	}
+	s.H, s.C = s.H[:n*m], s.C[:n*m]
+	if cap(s.bonus) < n {
+		s.bonus = make([]int32, n)
+	}
+}
+
+// cellInvalid marks DP cells where q[0..i] cannot be aligned within t[0..j].
+const cellInvalid = -1 << 30
+
+// alignV2 returns the best-scoring alignment of query within text. A match
+// must exist (callers narrow with matchWindow* first). bonus[j] is the positional
+// bonus of text[j]; slab holds the matrices, every cell of which is written
+// before it's read.
+//
+// H[i][j] is the best score aligning query[0..i] inside text[0..j], where
+// query[i] sits at or before j; trailing gap chars cost scoreGapStart then
+// scoreGapExtension each. C[i][j] is the length of the consecutive run
+// ending at (i, j) when the cell was produced by a match (0 otherwise) —
+// it lets a run inherit its first char's boundary bonus, so "launcher"
+// matched as one block beats the same letters scattered over boundaries.
+func alignV2[T byte | rune](slab *v2Slab, text, query []T, bonus []int32, withPositions bool) ([]int, int) {
+	n, m := len(text), len(query)
+	H, C := slab.H[:n*m], slab.C[:n*m]
+
+	for i := 0; i < m; i++ {
+		row := i * n
+		qc := query[i]
+		inGap := false
+		for j := 0; j < n; j++ {
+			gapScore := int32(cellInvalid)
+			if j > 0 && H[row+j-1] != cellInvalid {
+				if inGap {
+					gapScore = H[row+j-1] + scoreGapExtension
+				} else {
+					gapScore = H[row+j-1] + scoreGapStart
+				}
+			}
+
+			matchScore := int32(cellInvalid)
+			consecutive := int32(0)
+			if text[j] == qc {
+				b := bonus[j]
+				switch {
+				case i == 0:
+					matchScore = scoreMatch + b*bonusFirstCharMultiplier
+					consecutive = 1
+				case j > 0 && H[row-n+j-1] != cellInvalid:
+					consecutive = C[row-n+j-1] + 1
+					if consecutive > 1 {
+						// Inside a run: inherit the run's opening bonus
+						// unless this char starts a stronger word itself,
+						// in which case it starts a fresh run.
+						first := bonus[j-int(consecutive)+1]
+						if b >= bonusBoundary && b > first {
+							consecutive = 1
+						} else {
+							b = max(b, bonusConsecutive, first)
+						}
+					}
+					matchScore = H[row-n+j-1] + scoreMatch + b
+				}
+				if matchScore != cellInvalid && matchScore < gapScore {
+					matchScore = cellInvalid
+				}
+			}
+
+			if matchScore != cellInvalid {
+				H[row+j] = matchScore
+				C[row+j] = consecutive
+				inGap = false
+			} else {
+				H[row+j] = gapScore
+				C[row+j] = 0
+				inGap = gapScore != cellInvalid
+			}
+		}
+	}
+
+	// Best end column: the highest score in the last row. Trailing gaps only
+	// lower the score, so this lands on a matched column; ties keep the
+	// leftmost.
+	last := (m - 1) * n
+	bestJ := -1
+	best := int32(cellInvalid)
+	for j := 0; j < n; j++ {
+		if h := H[last+j]; h > best {
+			best, bestJ = h, j
+		}
+	}
+	if !withPositions {
+		return nil, int(best)
+	}
+
+	// Backtrack: a cell with C > 0 was produced by a match, so query[i]
+	// sits at j; otherwise the alignment continues to the left.
+	positions := make([]int, m)
+	i, j := m-1, bestJ
+	for i >= 0 && j >= 0 {
+		if C[i*n+j] > 0 {
+			positions[i] = j
+			i--
+		}
+		j--
+	}
+	return positions, int(best)
+}
+
+// scorePositionsASCII scores a fixed alignment (v1 greedy or exact
+// substring) with the same bonus model alignV2 optimizes, so scores from
+// both algorithms are comparable.
+func scorePositionsASCII(text string, positions []int) int {
+	if len(positions) == 0 {
+		return 0
+	}
+	classAt := func(i int) charClass {
+		if i < 0 {
+			return charWhite
+		}
+		return classOf(rune(text[i]))
+	}
+	return scorePositions(positions, classAt)
+}
+
+// scorePositionsRunes is scorePositionsASCII for text with non-ASCII
+// runes; positions are rune indices. It reads the text in place rather
+// than converting it to []rune, since scoring only looks at the runes
+// around each position.
+func scorePositionsRunes(text string, positions []int) int {
+	if len(positions) == 0 {
+		return 0
+	}
+	c := runeClasses{text: text}
+	return scorePositions(positions, c.at)
+}
+
+// runeClasses reads the char classes of a string by rune index, decoding
+// forward from the last index read. scorePositions asks for positions in
+// increasing order (and the index before each), so a pass over the
+// positions decodes the text at most once.
+type runeClasses struct {
+	text string
+	off  int       // byte offset of rune idx
+	idx  int       // rune index the cursor is at
+	prev charClass // class of rune idx-1
+}
+
+func (c *runeClasses) at(i int) charClass {
+	if i < 0 {
+		return charWhite
+	}
+	if i == c.idx-1 {
+		return c.prev
+	}
+	if i < c.idx {
+		*c = runeClasses{text: c.text} // unsorted positions: start over
+	}
+	for c.idx < i && c.off < len(c.text) {
+		r, size := utf8.DecodeRuneInString(c.text[c.off:])
+		c.prev = classOf(r)
+		c.off += size
+		c.idx++
+	}
+	if c.idx < i || c.off >= len(c.text) {
+		return charWhite
+	}
+	r, _ := utf8.DecodeRuneInString(c.text[c.off:])
+	return classOf(r)
+}
+
+func scorePositions(positions []int, classAt func(int) charClass) int {
+	bonusAt := func(i int) int32 { return bonusFor(classAt(i-1), classAt(i)) }
+
+	score := int32(0)
+	first := int32(0) // bonus of the current run's first char
+	prev := -2
+	for k, p := range positions {
+		b := bonusAt(p)
+		if p == prev+1 {
+			if b >= bonusBoundary && b > first {
+				first = b
+			} else {
+				b = max(b, bonusConsecutive, first)
+			}
+		} else {
+			if k > 0 {
+				gap := p - prev - 1
+				score += scoreGapStart + int32(gap-1)*scoreGapExtension
+			}
+			first = b
+		}
+		if k == 0 {
+			b *= bonusFirstCharMultiplier
+		}
+		score += scoreMatch + b
+		prev = p
+	}
+	return int(score)
+}
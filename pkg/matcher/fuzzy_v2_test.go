@@ -0,0 +1,170 @@
+package matcher
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+func newV2Matcher() *FuzzyMatcher {
+	return NewFuzzyMatcherWithOptions(Options{Algo: AlgoV2})
+}
+
+func initItem(text string) input.Item {
+	item := input.Item{Text: text, Raw: text}
+	item.Init()
+	return item
+}
+
+func TestFuzzyMatchV2_PrefersConsecutiveRun(t *testing.T) {
+	item := initItem("lib/launcher")
+
+	_, v1, _ := NewFuzzyMatcher(false, false).Match("launcher", item)
+	_, v2, _ := newV2Matcher().Match("launcher", item)
+
+	// v1 grabs the leading "l" of "lib" and scatters the rest.
+	if v1[0] != 0 {
+		t.Fatalf("v1 positions = %v, expected greedy start at 0", v1)
+	}
+	want := []int{4, 5, 6, 7, 8, 9, 10, 11}
+	if !reflect.DeepEqual(v2, want) {
+		t.Errorf("v2 positions = %v, want %v", v2, want)
+	}
+}
+
+func TestFuzzyMatchV2_PrefersWordBoundaries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		text  string
+		want  []int
+	}{
+		{"path separator", "mt", "summit/main_test.go", []int{7, 12}},
+		{"camelCase hump", "fb", "fooxfbar/FooBar.go", []int{9, 12}},
+		{"dash boundary", "gl", "goggle-launcher", []int{0, 7}},
+	}
+	m := newV2Matcher()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, positions, _ := m.Match(tt.query, initItem(tt.text))
+			if !ok {
+				t.Fatalf("%q should match %q", tt.query, tt.text)
+			}
+			if !reflect.DeepEqual(positions, tt.want) {
+				t.Errorf("positions = %v, want %v", positions, tt.want)
+			}
+		})
+	}
+}
+
+// v2 must accept exactly the items v1 accepts — only the alignment changes.
+func TestFuzzyMatchV2_SameMatchSetAsV1(t *testing.T) {
+	items := generateItems(2000)
+	v1 := NewFuzzyMatcher(false, false)
+	v2 := newV2Matcher()
+	for _, query := range []string{"handler", "hlp", "tst", "zzz", "cmd/main", "go"} {
+		for _, item := range items {
+			ok1, _, _ := v1.Match(query, item)
+			ok2, pos, _ := v2.Match(query, item)
+			if ok1 != ok2 {
+				t.Fatalf("query %q on %q: v1=%v v2=%v", query, item.Text, ok1, ok2)
+			}
+			if ok2 && len(pos) != len(query) {
+				t.Fatalf("query %q on %q: got %d positions", query, item.Text, len(pos))
+			}
+		}
+	}
+}
+
+// The v2 alignment is optimal under the shared bonus model, so it never
+// scores below v1's greedy alignment of the same item.
+func TestFuzzyMatchV2_ScoreAtLeastV1(t *testing.T) {
+	v1 := NewFuzzyMatcher(false, false)
+	v2 := newV2Matcher()
+	for _, text := range []string{"lib/launcher", "go/lib/goose-launcher", "FooBar.go", "a_b_c abc"} {
+		item := initItem(text)
+		for _, query := range []string{"l", "gl", "launcher", "fb", "abc"} {
+			ok, _, s1 := v1.Match(query, item)
+			if !ok {
+				continue
+			}
+			_, _, s2 := v2.Match(query, item)
+			if s2 < s1 {
+				t.Errorf("query %q on %q: v2 score %d < v1 score %d", query, text, s2, s1)
+			}
+		}
+	}
+}
+
+// A line too long for the alignment matrix (see maxV2Cells) falls back
+// to the greedy alignment rather than allocating megabytes for one item.
+func TestFuzzyMatchV2_LongLineFallsBackToV1(t *testing.T) {
+	text := "lib/" + strings.Repeat("x;", 60000) + "launcher"
+	item := initItem(text)
+	ok, positions, score := newV2Matcher().Match("launcher", item)
+	if !ok || len(positions) != 8 {
+		t.Fatalf("Match = %v, %v; want 8 positions", ok, positions)
+	}
+	_, v1, v1Score := matchV1(termFuzzy, text, item.LowerText, "launcher", true, true, true)
+	if !reflect.DeepEqual(positions, v1) || score != v1Score {
+		t.Errorf("got %v (score %d), want v1's %v (score %d)", positions, score, v1, v1Score)
+	}
+}
+
+// Scoring a non-ASCII text in place agrees with scoring its runes.
+func TestScorePositionsRunes_InPlace(t *testing.T) {
+	text := "Ünïcödé/fooBar ñame_42.txt"
+	runes := []rune(text)
+	ref := func(positions []int) int {
+		return scorePositions(positions, func(i int) charClass {
+			if i < 0 || i >= len(runes) {
+				return charWhite
+			}
+			return classOf(runes[i])
+		})
+	}
+	for _, positions := range [][]int{
+		{0}, {0, 1, 2}, {8, 11, 12}, {15, 16, 17, 18}, {20, 21, 25}, {3, 1}, {24, 25, 26},
+	} {
+		if got, want := scorePositionsRunes(text, positions), ref(positions); got != want {
+			t.Errorf("positions %v: score %d, want %d", positions, got, want)
+		}
+	}
+}
+
+func TestFuzzyMatchV2_NonASCII(t *testing.T) {
+	ok, positions, _ := newV2Matcher().Match("ué", initItem("résumé/ünité"))
+	if !ok {
+		t.Fatal("expected match")
+	}
+	// "ü" isn't "u", so the only "u" is in "résumé"; v2 then prefers the
+	// trailing "é" closest after it.
+	want := []int{3, 5}
+	if !reflect.DeepEqual(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+}
+
+func TestFuzzyMatchV2_MatchOnly(t *testing.T) {
+	m := newV2Matcher()
+	if !m.MatchOnly("gl", initItem("goose-launcher")) {
+		t.Error("expected MatchOnly to accept")
+	}
+	if m.MatchOnly("lg", initItem("goose-launcher")) {
+		t.Error("expected MatchOnly to reject out-of-order query")
+	}
+}
+
+func TestParseAlgo(t *testing.T) {
+	for in, want := range map[string]Algo{"": AlgoV1, "v1": AlgoV1, "v2": AlgoV2} {
+		got, err := ParseAlgo(in)
+		if err != nil || got != want {
+			t.Errorf("ParseAlgo(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseAlgo("v3"); err == nil {
+		t.Error("expected error for unknown algo")
+	}
+}
@@ -0,0 +1,153 @@
+package matcher
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// Matcher decides whether an item matches a query. The window, ranker and
+// daemon only depend on this interface, so new algorithms plug in through
+// Register without touching pkg/ui.
+//
+// Implementations must be safe for concurrent use: filtering may call Match
+// from several goroutines with the same query.
+type Matcher interface {
+	// Match reports whether query matches item, the rune indices into
+	// item.Text to highlight (sorted, may be nil), and a score where higher
+	// is better. Matchers that don't score return 0; a negative score marks
+	// an approximate hit, which the ranker places after all others.
+	Match(query string, item input.Item) (ok bool, positions []int, score int)
+}
+
+// MatchOnlyMatcher is implemented by matchers with a cheaper path for
+// callers that only need the yes/no answer (counting, or filtering with
+// highlighting and ranking off).
+type MatchOnlyMatcher interface {
+	MatchOnly(query string, item input.Item) bool
+}
+
+// PositionsMatcher is implemented by matchers that can find the highlight
+// positions without scoring them, for filtering with highlighting on and
+// ranking off.
+type PositionsMatcher interface {
+	MatchPositions(query string, item input.Item) (bool, []int)
+}
+
+// QueryValidator is implemented by matchers that can reject a query
+// outright, e.g. a regex that doesn't compile. The UI shows the error and
+// keeps its previous results instead of filtering everything out.
+type QueryValidator interface {
+	QueryError(query string) error
+}
+
+// Narrower is implemented by matchers whose results can only shrink as the
+// query grows. When Narrows(prev, next) is true, every item matching next
+// also matches prev, so the UI searches prev's results instead of every
+// item. Answering false is always safe.
+type Narrower interface {
+	Narrows(prev, next string) bool
+}
+
+// Narrows reports whether m guarantees next's matches are a subset of
+// prev's; false for matchers that don't implement Narrower.
+func Narrows(m Matcher, prev, next string) bool {
+	if n, ok := m.(Narrower); ok {
+		return n.Narrows(prev, next)
+	}
+	return false
+}
+
+// MatchOnlyFunc returns m's yes/no matcher: its MatchOnly method when it
+// has one, otherwise Match with the extras discarded. Resolve it once per
+// filter pass rather than type-asserting per item.
+func MatchOnlyFunc(m Matcher) func(query string, item input.Item) bool {
+	if mo, ok := m.(MatchOnlyMatcher); ok {
+		return mo.MatchOnly
+	}
+	return func(query string, item input.Item) bool {
+		ok, _, _ := m.Match(query, item)
+		return ok
+	}
+}
+
+// MatchPositionsFunc is MatchOnlyFunc for PositionsMatcher: its
+// MatchPositions method when m has one, otherwise Match without the score.
+func MatchPositionsFunc(m Matcher) func(query string, item input.Item) (bool, []int) {
+	if pm, ok := m.(PositionsMatcher); ok {
+		return pm.MatchPositions
+	}
+	return func(query string, item input.Item) (bool, []int) {
+		ok, positions, _ := m.Match(query, item)
+		return ok, positions
+	}
+}
+
+// QueryError returns m's verdict on query, or nil when m accepts any query.
+func QueryError(m Matcher, query string) error {
+	if v, ok := m.(QueryValidator); ok {
+		return v.QueryError(query)
+	}
+	return nil
+}
+
+// Factory builds a matcher from the shared flags (case mode, algorithm,
+// extended syntax, normalization). Factories may ignore options that don't
+// apply to them.
+type Factory func(opts Options) Matcher
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a matcher available by name (e.g. to --matcher).
+// Registering an existing name replaces it, so callers can override the
+// built-ins.
+func Register(name string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = f
+}
+
+// New builds the matcher registered under name.
+func New(name string, opts Options) (Matcher, error) {
+	registryMu.RLock()
+	f, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown matcher %q (available: %v)", name, Names())
+	}
+	return f(opts), nil
+}
+
+// Names lists the registered matchers in sorted order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The built-in modes are FuzzyMatcher configurations; the name overrides
+// whichever of Exact/Regex the options carried.
+func init() {
+	Register("exact", func(opts Options) Matcher {
+		opts.Exact, opts.Regex = true, false
+		return NewFuzzyMatcherWithOptions(opts)
+	})
+	Register("fuzzy", func(opts Options) Matcher {
+		opts.Exact, opts.Regex = false, false
+		return NewFuzzyMatcherWithOptions(opts)
+	})
+	Register("regex", func(opts Options) Matcher {
+		opts.Regex = true
+		return NewFuzzyMatcherWithOptions(opts)
+	})
+}
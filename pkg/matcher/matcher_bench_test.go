@@ -1,8 +1,10 @@
 package matcher
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"unsafe"
 
 	"github.com/sam33r/goose-launcher/pkg/input"
 )
@@ -92,6 +94,21 @@ func BenchmarkFuzzyMatch_VeryLargeDataset(b *testing.B) {
 	}
 }
 
+// BenchmarkFuzzyMatchV2_LargeDataset tests best-alignment matching with 100k
+// items. Compare against BenchmarkFuzzyMatch_LargeDataset for the v2 cost.
+func BenchmarkFuzzyMatchV2_LargeDataset(b *testing.B) {
+	items := generateItems(100000)
+	matcher := NewFuzzyMatcherWithOptions(Options{Algo: AlgoV2})
+	query := "handler"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, item := range items {
+			matcher.Match(query, item)
+		}
+	}
+}
+
 // BenchmarkFuzzyMatch_ShortQuery tests short queries (1-2 chars)
 func BenchmarkFuzzyMatch_ShortQuery(b *testing.B) {
 	items := generateItems(10000)
@@ -201,7 +218,7 @@ func BenchmarkFilterOperation(b *testing.B) {
 		var filtered []input.Item
 		var positions [][]int
 		for _, item := range items {
-			match, pos := matcher.Match(query, item)
+			match, pos, _ := matcher.Match(query, item)
 			if match {
 				filtered = append(filtered, item)
 				positions = append(positions, pos)
@@ -209,3 +226,117 @@ func BenchmarkFilterOperation(b *testing.B) {
 		}
 	}
 }
+
+// benchmarkFilter runs a full filter pass (positions on, as with
+// highlighting) through either the serial or the sharded path.
+func benchmarkFilter(b *testing.B, n int, parallel bool) {
+	items := generateItems(n)
+	matcher := NewFuzzyMatcher(false, false)
+	query := "handler"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if parallel {
+			Filter(matcher, query, items, WantScore)
+		} else {
+			FilterSerial(matcher, query, items, WantScore)
+		}
+	}
+}
+
+// BenchmarkFilterSerial_100k / BenchmarkFilterParallel_100k compare the
+// single-goroutine walk with the GOMAXPROCS-sharded one.
+func BenchmarkFilterSerial_100k(b *testing.B)   { benchmarkFilter(b, 100000, false) }
+func BenchmarkFilterParallel_100k(b *testing.B) { benchmarkFilter(b, 100000, true) }
+
+// BenchmarkFilterSerial_1M / BenchmarkFilterParallel_1M are the same at the
+// size where serial filtering visibly stalls typing.
+func BenchmarkFilterSerial_1M(b *testing.B)   { benchmarkFilter(b, 1000000, false) }
+func BenchmarkFilterParallel_1M(b *testing.B) { benchmarkFilter(b, 1000000, true) }
+
+// BenchmarkFilterTypos_100k measures --typos=2 with a misspelled query
+// ("hnadler"): every item that doesn't match as typed goes through the
+// length/character prefilter, and only the survivors pay for the edit
+// distance. Compare with BenchmarkFilterSerial_100k.
+func BenchmarkFilterTypos_100k(b *testing.B) {
+	items := generateItems(100000)
+	matcher := NewFuzzyMatcherWithOptions(Options{Exact: true, Typos: 2})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FilterSerial(matcher, "hnadler", items, WantScore)
+	}
+}
+
+// benchmarkMaskPrefilter filters 1M items for "yaml", which only 3 of the
+// 10 generated path shapes contain every letter of, with or without the
+// per-item character masks Init computes. It also reports the size of
+// input.Item, which the mask grows by 8 bytes.
+func benchmarkMaskPrefilter(b *testing.B, exact, masked bool) {
+	items := generateItems(1000000)
+	if !masked {
+		for i := range items {
+			items[i].Mask = 0
+		}
+	}
+	matcher := NewFuzzyMatcherWithOptions(Options{Exact: exact})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FilterSerial(matcher, "yaml", items, WantScore)
+	}
+	b.ReportMetric(float64(unsafe.Sizeof(input.Item{})), "B/item")
+}
+
+func BenchmarkMaskPrefilter_Exact_1M(b *testing.B)    { benchmarkMaskPrefilter(b, true, true) }
+func BenchmarkMaskPrefilter_ExactOff_1M(b *testing.B) { benchmarkMaskPrefilter(b, true, false) }
+func BenchmarkMaskPrefilter_Fuzzy_1M(b *testing.B)    { benchmarkMaskPrefilter(b, false, true) }
+func BenchmarkMaskPrefilter_FuzzyOff_1M(b *testing.B) { benchmarkMaskPrefilter(b, false, false) }
+
+// BenchmarkTrigramIndexBuild_2M measures indexing a 2M-line input, as the
+// UI does in the background once stdin hits EOF, and reports the index
+// size.
+func BenchmarkTrigramIndexBuild_2M(b *testing.B) {
+	items := generateItems(2000000)
+
+	var ix *TrigramIndex
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix, _ = BuildTrigramIndex(context.Background(), items)
+	}
+	b.ReportMetric(float64(ix.Memory())/(1<<20), "MB")
+}
+
+// benchmarkTrigramFilter runs an exact-mode pass over 2M items, either
+// scanning all of them or only the index's candidates.
+func benchmarkTrigramFilter(b *testing.B, query string, indexed bool) {
+	items := generateItems(2000000)
+	ix, _ := BuildTrigramIndex(context.Background(), items)
+	matcher := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart, Exact: true, Extended: true})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !indexed {
+			FilterContext(context.Background(), matcher, query, items, WantScore)
+			continue
+		}
+		cands, _ := Candidates(matcher, ix, query)
+		RefineContext(context.Background(), matcher, query, items, cands, WantScore)
+	}
+}
+
+// "helper_1234" is a typical symbol lookup (11 hits); "handler" hits 10%
+// of the items.
+func BenchmarkTrigramFilter_Selective_2M(b *testing.B) {
+	benchmarkTrigramFilter(b, "helper_1234", true)
+}
+func BenchmarkTrigramFilterScan_Selective_2M(b *testing.B) {
+	benchmarkTrigramFilter(b, "helper_1234", false)
+}
+func BenchmarkTrigramFilter_Broad_2M(b *testing.B)     { benchmarkTrigramFilter(b, "handler", true) }
+func BenchmarkTrigramFilterScan_Broad_2M(b *testing.B) { benchmarkTrigramFilter(b, "handler", false) }
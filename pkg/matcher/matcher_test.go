@@ -0,0 +1,101 @@
+package matcher
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// prefixMatcher is a minimal custom matcher: case-sensitive prefix, no
+// MatchOnly or QueryError methods.
+type prefixMatcher struct{}
+
+func (prefixMatcher) Match(query string, item input.Item) (bool, []int, int) {
+	if !strings.HasPrefix(item.Text, query) {
+		return false, nil, 0
+	}
+	positions := make([]int, len(query))
+	for i := range positions {
+		positions[i] = i
+	}
+	return true, positions, len(query)
+}
+
+func TestRegistry_BuiltIns(t *testing.T) {
+	item := initItem("goose-launcher")
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"exact", "gsl", false},
+		{"exact", "launch", true},
+		{"fuzzy", "gsl", true},
+		{"regex", "^go+se", true},
+		{"regex", "gsl", false},
+	}
+	for _, tt := range tests {
+		m, err := New(tt.name, Options{Case: CaseSmart})
+		if err != nil {
+			t.Fatalf("New(%q): %v", tt.name, err)
+		}
+		if ok, _, _ := m.Match(tt.query, item); ok != tt.want {
+			t.Errorf("%s matcher on %q = %v, want %v", tt.name, tt.query, ok, tt.want)
+		}
+	}
+}
+
+// The name wins over the mode flags carried in Options.
+func TestRegistry_NameOverridesMode(t *testing.T) {
+	m, err := New("fuzzy", Options{Exact: true, Regex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts := m.(*FuzzyMatcher).Options(); opts.Exact || opts.Regex {
+		t.Errorf("fuzzy matcher options = %+v, want neither Exact nor Regex", opts)
+	}
+}
+
+func TestRegistry_CustomAndUnknown(t *testing.T) {
+	Register("test-prefix", func(Options) Matcher { return prefixMatcher{} })
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "test-prefix")
+		registryMu.Unlock()
+	}()
+
+	m, err := New("test-prefix", Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ok, positions, score := m.Match("goo", initItem("goose"))
+	if !ok || !reflect.DeepEqual(positions, []int{0, 1, 2}) || score != 3 {
+		t.Errorf("Match = %v, %v, %d", ok, positions, score)
+	}
+
+	if got := Names(); !reflect.DeepEqual(got, []string{"exact", "fuzzy", "regex", "test-prefix"}) {
+		t.Errorf("Names() = %v", got)
+	}
+
+	if _, err := New("nope", Options{}); err == nil {
+		t.Error("expected an error for an unregistered matcher")
+	}
+}
+
+func TestMatchOnlyFuncAndQueryError_Fallbacks(t *testing.T) {
+	var custom Matcher = prefixMatcher{}
+	matchOnly := MatchOnlyFunc(custom)
+	if !matchOnly("go", initItem("goose")) || matchOnly("se", initItem("goose")) {
+		t.Error("MatchOnlyFunc should fall back to Match")
+	}
+	if err := QueryError(custom, "(("); err != nil {
+		t.Errorf("matchers without QueryError accept everything, got %v", err)
+	}
+
+	var regex Matcher = newRegexMatcher()
+	if err := QueryError(regex, "(("); err == nil {
+		t.Error("expected the regex matcher's compile error")
+	}
+}
@@ -0,0 +1,161 @@
+package matcher
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+const (
+	// parallelThreshold is the input size below which Filter stays on the
+	// calling goroutine. Under ~10k items a serial pass takes a couple of
+	// milliseconds and goroutine fan-out buys nothing.
+	parallelThreshold = 10000
+	// minShardSize caps the worker count so each goroutine gets enough
+	// items to amortize its startup.
+	minShardSize = 2500
+)
+
+// Want is how much a filter pass computes for each hit.
+type Want uint8
+
+const (
+	// WantMatch only finds the hits (the MatchOnly path).
+	WantMatch Want = iota
+	// WantPositions adds the highlight positions, without scoring.
+	WantPositions
+	// WantScore adds the positions and the matcher score, for ranking.
+	WantScore
+)
+
+// Result is one hit from Filter. Index points into the items passed in;
+// Positions are only set when Filter was asked for them, Score only with
+// WantScore.
+type Result struct {
+	Index     int
+	Positions []int
+	Score     int
+}
+
+// Filter matches query against every item and returns the hits in input
+// order. Large inputs are split into one contiguous shard per GOMAXPROCS
+// worker and the shard results concatenated, so the output is identical to
+// FilterSerial. want selects Match, or one of the cheaper MatchPositions
+// and MatchOnly paths.
+//
+// m must be safe for concurrent use (see Matcher).
+func Filter(m Matcher, query string, items []input.Item, want Want) []Result {
+	results, _ := FilterContext(context.Background(), m, query, items, want)
+	return results
+}
+
+// FilterContext is Filter that gives up once ctx is done, returning
+// ctx.Err(). The UI's filter worker cancels a pass as soon as a newer
+// query supersedes it.
+func FilterContext(ctx context.Context, m Matcher, query string, items []input.Item, want Want) ([]Result, error) {
+	return filterSharded(ctx, m, query, items, nil, want, runtime.GOMAXPROCS(0))
+}
+
+// RefineContext is FilterContext restricted to the items in prev, the
+// results of an earlier pass over the same items. When the matcher says
+// query narrows that pass's query (see Narrower), the output is identical
+// to filtering all items, at a fraction of the cost.
+func RefineContext(ctx context.Context, m Matcher, query string, items []input.Item, prev []Result, want Want) ([]Result, error) {
+	if prev == nil {
+		return nil, ctx.Err()
+	}
+	return filterSharded(ctx, m, query, items, prev, want, runtime.GOMAXPROCS(0))
+}
+
+// FilterSerial is Filter on the calling goroutine. It's the reference the
+// parallel path must agree with.
+func FilterSerial(m Matcher, query string, items []input.Item, want Want) []Result {
+	results, _ := filterRange(context.Background(), m, query, items, nil, 0, len(items), want, nil)
+	return results
+}
+
+// filterSharded matches items, or only the items named by subset when it's
+// non-nil, splitting the work across up to workers goroutines.
+func filterSharded(ctx context.Context, m Matcher, query string, items []input.Item, subset []Result, want Want, workers int) ([]Result, error) {
+	n := len(items)
+	if subset != nil {
+		n = len(subset)
+	}
+	workers = min(workers, n/minShardSize)
+	if workers < 2 || n < parallelThreshold {
+		return filterRange(ctx, m, query, items, subset, 0, n, want, nil)
+	}
+
+	shards := make([][]Result, workers)
+	size := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo := w * size
+		hi := min(lo+size, n)
+		if lo >= hi {
+			break
+		}
+		wg.Add(1)
+		go func(w, lo, hi int) {
+			defer wg.Done()
+			shards[w], _ = filterRange(ctx, m, query, items, subset, lo, hi, want, nil)
+		}(w, lo, hi)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, s := range shards {
+		total += len(s)
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	out := make([]Result, 0, total)
+	for _, s := range shards {
+		out = append(out, s...)
+	}
+	return out, nil
+}
+
+// cancelCheckInterval is how many items filterRange matches between
+// cancellation checks — often enough to stop within a millisecond or so,
+// rarely enough that the check doesn't show up in profiles.
+const cancelCheckInterval = 1024
+
+// filterRange matches positions [lo, hi) of items (or of subset, which
+// names items by index) and appends the hits to dst.
+func filterRange(ctx context.Context, m Matcher, query string, items []input.Item, subset []Result, lo, hi int, want Want, dst []Result) ([]Result, error) {
+	matchOnly := MatchOnlyFunc(m)
+	matchPositions := MatchPositionsFunc(m)
+	for i := lo; i < hi; i++ {
+		if (i-lo)%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		idx := i
+		if subset != nil {
+			idx = subset[i].Index
+		}
+		switch want {
+		case WantMatch:
+			if matchOnly(query, items[idx]) {
+				dst = append(dst, Result{Index: idx})
+			}
+		case WantPositions:
+			if ok, positions := matchPositions(query, items[idx]); ok {
+				dst = append(dst, Result{Index: idx, Positions: positions})
+			}
+		default:
+			if ok, positions, score := m.Match(query, items[idx]); ok {
+				dst = append(dst, Result{Index: idx, Positions: positions, Score: score})
+			}
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,80 @@
+package matcher
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// The sharded path must return exactly what a serial walk returns: same
+// items, same order, same positions and scores.
+func TestFilter_ShardedMatchesSerial(t *testing.T) {
+	items := generateItems(20000)
+	matchers := map[string]Matcher{
+		"fuzzy": NewFuzzyMatcher(false, false),
+		"exact": NewFuzzyMatcher(false, true),
+		"v2":    newV2Matcher(),
+		"regex": newRegexMatcher(),
+	}
+	for name, m := range matchers {
+		for _, query := range []string{"handler", "hlp", "_4", "zzz"} {
+			for _, detail := range []Want{WantMatch, WantPositions, WantScore} {
+				want := FilterSerial(m, query, items, detail)
+				for _, workers := range []int{2, 3, 8} {
+					got, err := filterSharded(context.Background(), m, query, items, nil, detail, workers)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Fatalf("%s %q want=%d workers=%d: sharded result differs from serial (%d vs %d hits)",
+							name, query, detail, workers, len(got), len(want))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFilter_SmallInputStaysSerial(t *testing.T) {
+	items := generateItems(100)
+	got := Filter(NewFuzzyMatcher(false, true), "handler", items, WantScore)
+	if len(got) != 10 {
+		t.Fatalf("expected 10 hits, got %d", len(got))
+	}
+	for _, r := range got {
+		if items[r.Index].Index%10 != 0 || len(r.Positions) != len("handler") {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+}
+
+func TestFilterContext_Cancelled(t *testing.T) {
+	items := generateItems(20000)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := FilterContext(ctx, NewFuzzyMatcher(false, false), "handler", items, WantScore)
+	if err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if results != nil {
+		t.Errorf("expected no results from a cancelled pass, got %d", len(results))
+	}
+}
+
+// Refining the hits of a shorter query gives the same answer as filtering
+// everything, on both the serial and sharded paths.
+func TestRefineContext_MatchesFullFilter(t *testing.T) {
+	items := generateItems(40000)
+	m := NewFuzzyMatcher(false, false)
+	for _, q := range [][2]string{{"h", "handler"}, {"han", "hand"}, {"_", "_4"}, {"zz", "zzz"}} {
+		prev := Filter(m, q[0], items, WantMatch)
+		got, err := RefineContext(context.Background(), m, q[1], items, prev, WantScore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := Filter(m, q[1], items, WantScore); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q -> %q: refined %d hits, full filter %d", q[0], q[1], len(got), len(want))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package matcher
+
+import (
+	"regexp"
+	"sync"
+	"unicode/utf8"
+)
+
+// regexCacheSize bounds the compiled-regex cache. Typing a pattern one
+// character at a time compiles every prefix once; backspacing and the
+// runtime toggle then hit the cache instead of recompiling.
+const regexCacheSize = 64
+
+// regexCache maps a query to its compiled form (or compile error). Shared
+// by every filter pass of one matcher; the per-item hot path never touches
+// it because the compiled regex rides along in the cached pattern.
+type regexCache struct {
+	mu      sync.Mutex
+	entries map[string]regexEntry
+}
+
+type regexEntry struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// compile returns the compiled regex for query, folding case when
+// ignoreCase is set. Errors are reported against the query as typed, not
+// the (?i)-prefixed expression we actually compile.
+func (c *regexCache) compile(query string, ignoreCase bool) (*regexp.Regexp, error) {
+	expr := query
+	if ignoreCase {
+		expr = "(?i)" + query
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[expr]; ok {
+		return e.re, e.err
+	}
+	if c.entries == nil || len(c.entries) >= regexCacheSize {
+		c.entries = make(map[string]regexEntry)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil && ignoreCase {
+		if _, qerr := regexp.Compile(query); qerr != nil {
+			err = qerr
+		}
+	}
+	c.entries[expr] = regexEntry{re: re, err: err}
+	return re, err
+}
+
+// parseRegexPattern builds the pattern for --regex mode: the whole query is
+// one regular expression, with smart case deciding the (?i) flag.
+func (m *FuzzyMatcher) parseRegexPattern(query string) *pattern {
+	re, err := m.regexes.compile(query, !m.caseSensitiveFor(query))
+	return &pattern{query: query, re: re, err: err}
+}
+
+// matchRegex matches a compiled regex against the original item text.
+// Highlights come from the capture groups when the expression has any that
+// participated, otherwise from the whole match. Zero-width matches (e.g.
+// "^") accept the item without highlighting anything.
+func matchRegex(re *regexp.Regexp, text string, ascii, withPositions, withScore bool) (bool, []int, int) {
+	if !withPositions && !withScore {
+		return re.MatchString(text), nil, 0
+	}
+	loc := re.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return false, nil, 0
+	}
+
+	spans := loc[:2]
+	for i := 2; i+1 < len(loc); i += 2 {
+		if loc[i] >= 0 && loc[i+1] > loc[i] {
+			spans = loc[2:]
+			break
+		}
+	}
+
+	var positions []int
+	for i := 0; i+1 < len(spans); i += 2 {
+		start, end := spans[i], spans[i+1]
+		if start < 0 || end <= start {
+			continue
+		}
+		positions = appendRuneRange(positions, text, start, end, ascii)
+	}
+	if len(spans) > 2 {
+		// Groups can nest or overlap ("(a(b))").
+		positions = mergePositions(positions)
+	}
+
+	score := 0
+	if withScore && len(positions) > 0 {
+		score = scoreFixed(text, ascii, positions)
+	}
+	return true, positions, score
+}
+
+// appendRuneRange appends the rune indices covering text[start:end].
+func appendRuneRange(positions []int, text string, start, end int, ascii bool) []int {
+	if ascii {
+		for i := start; i < end; i++ {
+			positions = append(positions, i)
+		}
+		return positions
+	}
+	r := utf8.RuneCountInString(text[:start])
+	for range text[start:end] {
+		positions = append(positions, r)
+		r++
+	}
+	return positions
+}
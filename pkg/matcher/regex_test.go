@@ -0,0 +1,96 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newRegexMatcher() *FuzzyMatcher {
+	return NewFuzzyMatcherWithOptions(Options{Case: CaseSmart, Regex: true})
+}
+
+func TestRegex_Positions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		text  string
+		want  []int
+	}{
+		{"whole match", `ma.n`, "src/main.go", []int{4, 5, 6, 7}},
+		{"capture group", `(\w+)\.go$`, "src/main.go", []int{4, 5, 6, 7}},
+		{"several groups", `^(s)rc/(m)`, "src/main.go", []int{0, 4}},
+		{"nested groups", `((ma)in)`, "src/main.go", []int{4, 5, 6, 7}},
+		{"non-ASCII", `é.`, "résumé!", []int{1, 2}},
+		{"zero width", `^`, "anything", nil},
+	}
+	m := newRegexMatcher()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, positions, _ := m.Match(tt.query, initItem(tt.text))
+			if !ok {
+				t.Fatalf("%q should match %q", tt.query, tt.text)
+			}
+			if !reflect.DeepEqual(positions, tt.want) {
+				t.Errorf("positions = %v, want %v", positions, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegex_SmartCase(t *testing.T) {
+	m := newRegexMatcher()
+	if ok, _, _ := m.Match(`readme\.md`, initItem("README.md")); !ok {
+		t.Error("lowercase regex should match case-insensitively")
+	}
+	if ok, _, _ := m.Match(`Readme`, initItem("README.md")); ok {
+		t.Error("regex with an uppercase letter should be case-sensitive")
+	}
+}
+
+// Extended-syntax operators are plain regex characters in regex mode.
+func TestRegex_IgnoresExtendedSyntax(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Regex: true, Extended: true})
+	if ok, _, _ := m.Match(`^src|docs$`, initItem("src/main.go")); !ok {
+		t.Error("regex alternation should match")
+	}
+	if ok, _, _ := m.Match(`main !test`, initItem("src/main.go")); ok {
+		t.Error("extended operators should not apply in regex mode")
+	}
+}
+
+func TestRegex_InvalidPattern(t *testing.T) {
+	m := newRegexMatcher()
+	if err := m.QueryError("(foo"); err == nil {
+		t.Fatal("expected an error for an unbalanced group")
+	} else if want := "error parsing regexp: missing closing ): `(foo`"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if ok, _, _ := m.Match("(foo", initItem("(foo")); ok {
+		t.Error("an invalid regex should match nothing")
+	}
+	if err := m.QueryError("(foo)"); err != nil {
+		t.Errorf("unexpected error for a valid regex: %v", err)
+	}
+	if err := NewFuzzyMatcher(false, false).QueryError("(foo"); err != nil {
+		t.Errorf("non-regex matcher should accept any query, got %v", err)
+	}
+}
+
+func TestRegex_CompiledOncePerQuery(t *testing.T) {
+	var c regexCache
+	a, _ := c.compile("ma.n", true)
+	b, _ := c.compile("ma.n", true)
+	if a != b {
+		t.Error("expected the cached regex to be reused")
+	}
+	if s, _ := c.compile("ma.n", false); s == a {
+		t.Error("case-sensitive compile must not share the folded entry")
+	}
+}
+
+func TestOptionsRoundTrip(t *testing.T) {
+	opts := Options{Case: CaseSmart, Exact: true, Algo: AlgoV2, Extended: true, Normalize: true, Regex: true}
+	if got := NewFuzzyMatcherWithOptions(opts).Options(); got != opts {
+		t.Errorf("Options() = %+v, want %+v", got, opts)
+	}
+}
@@ -0,0 +1,279 @@
+package matcher
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// trigramSpace is the number of distinct trigrams of 7-bit ASCII.
+const trigramSpace = 1 << 21
+
+// TrigramIndex maps every three-byte sequence of the items' lowercased
+// text to the items containing it, so a substring term of three or more
+// characters only needs to search the items holding all of its trigrams.
+// It's built once the item set stops changing (stdin hit EOF) and is
+// immutable afterwards, so filter workers share it freely.
+//
+// Only ASCII items are indexed; the rest are candidates for every query.
+type TrigramIndex struct {
+	n int
+	// Postings in CSR form: the items containing trigram k, ascending,
+	// are postings[offsets[k]:offsets[k+1]].
+	offsets  []uint32
+	postings []int32
+	// always lists the items that aren't indexed.
+	always []int32
+	// initials is the CharMask of each item's word-initial characters:
+	// the only items an acronym term ("gld") can match without containing
+	// it as a substring.
+	initials []uint64
+}
+
+// trigramKey packs three lowercased ASCII bytes into an index slot.
+func trigramKey(a, b, c byte) uint32 {
+	return uint32(a&0x7f)<<14 | uint32(b&0x7f)<<7 | uint32(c&0x7f)
+}
+
+// indexSubject returns the text the matcher searches for item: its --nth
+// fields when set. ok is false when the item can't be indexed (non-ASCII,
+// or built without Init).
+func indexSubject(item *input.Item) (text, lower string, ok bool) {
+	if s := item.Search; s != nil {
+		return s.Text, s.LowerText, s.ASCII
+	}
+	return item.Text, item.LowerText, item.ASCII
+}
+
+// BuildTrigramIndex indexes items, or returns ctx.Err() if ctx is done
+// first. Two passes over the text: one counting each trigram's items to
+// size the postings exactly, one filling them.
+func BuildTrigramIndex(ctx context.Context, items []input.Item) (*TrigramIndex, error) {
+	ix := &TrigramIndex{
+		n:        len(items),
+		offsets:  make([]uint32, trigramSpace+1),
+		initials: make([]uint64, len(items)),
+	}
+	// last[k] is the last item counted for trigram k, plus one, so an
+	// item repeating a trigram is counted once.
+	last := make([]int32, trigramSpace)
+	for i := range items {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		text, lower, ok := indexSubject(&items[i])
+		if !ok {
+			ix.always = append(ix.always, int32(i))
+			ix.initials[i] = ^uint64(0)
+			continue
+		}
+		ix.initials[i] = initialsMask(text, lower)
+		for j := 0; j+2 < len(lower); j++ {
+			k := trigramKey(lower[j], lower[j+1], lower[j+2])
+			if last[k] != int32(i)+1 {
+				last[k] = int32(i) + 1
+				ix.offsets[k+1]++
+			}
+		}
+	}
+	for k := 1; k <= trigramSpace; k++ {
+		ix.offsets[k] += ix.offsets[k-1]
+	}
+
+	ix.postings = make([]int32, ix.offsets[trigramSpace])
+	fill := last // reused: next free slot of each posting list
+	for k := range fill {
+		fill[k] = int32(ix.offsets[k])
+	}
+	for i := range items {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		_, lower, ok := indexSubject(&items[i])
+		if !ok {
+			continue
+		}
+		for j := 0; j+2 < len(lower); j++ {
+			k := trigramKey(lower[j], lower[j+1], lower[j+2])
+			if f := fill[k]; f == int32(ix.offsets[k]) || ix.postings[f-1] != int32(i) {
+				ix.postings[f] = int32(i)
+				fill[k]++
+			}
+		}
+	}
+	return ix, nil
+}
+
+// initialsMask is the CharMask of the characters of text that start a
+// word, by the same rule as matchAcronym.
+func initialsMask(text, lower string) uint64 {
+	var mask uint64
+	prev := charWhite
+	for i := 0; i < len(text); i++ {
+		cur := classOf(rune(text[i]))
+		if isWordStart(prev, cur) {
+			mask |= input.CharMask(lower[i : i+1])
+		}
+		prev = cur
+	}
+	return mask
+}
+
+// Len is the number of items indexed. The index answers queries only for
+// exactly those items.
+func (ix *TrigramIndex) Len() int { return ix.n }
+
+// Memory is the index's approximate size in bytes.
+func (ix *TrigramIndex) Memory() int {
+	return 4*len(ix.offsets) + 4*len(ix.postings) + 4*len(ix.always) + 8*len(ix.initials)
+}
+
+// IndexedMatcher is implemented by matchers that can use a TrigramIndex
+// to narrow a query to candidate items before matching.
+type IndexedMatcher interface {
+	// Candidates returns, in ascending order, a superset of the items of
+	// ix that match query; ok is false when the index can't narrow query
+	// and every item has to be searched.
+	Candidates(ix *TrigramIndex, query string) (candidates []Result, ok bool)
+}
+
+// Candidates returns m's candidates for query from ix, ready to pass as
+// prev to RefineContext; ok is false for matchers that don't implement
+// IndexedMatcher or can't use the index for query.
+func Candidates(m Matcher, ix *TrigramIndex, query string) ([]Result, bool) {
+	im, isIndexed := m.(IndexedMatcher)
+	if !isIndexed || ix == nil {
+		return nil, false
+	}
+	return im.Candidates(ix, query)
+}
+
+// Candidates narrows query with ix. Only substring terms — plain terms in
+// exact mode, 'quoted ones in fuzzy mode, and anchored terms — of three or
+// more characters can use it; an AND group narrows the candidates when
+// every alternative in it does. Fuzzy, negated and approximate (--typos)
+// terms don't narrow anything.
+func (m *FuzzyMatcher) Candidates(ix *TrigramIndex, query string) ([]Result, bool) {
+	if m.regex || query == "" {
+		return nil, false
+	}
+	var (
+		cands    []int32
+		narrowed bool
+	)
+	for _, group := range m.pattern(query).groups {
+		var union []int32
+		ok := true
+		for i := range group {
+			c, termOK := ix.termCandidates(&group[i])
+			if !termOK {
+				ok = false
+				break
+			}
+			union = unionSorted(union, c)
+		}
+		if !ok {
+			continue
+		}
+		if narrowed {
+			cands = intersectSorted(cands, union)
+		} else {
+			cands, narrowed = union, true
+		}
+	}
+	if !narrowed {
+		return nil, false
+	}
+	results := make([]Result, len(cands))
+	for i, c := range cands {
+		results[i].Index = int(c)
+	}
+	return results, true
+}
+
+// termCandidates is the sorted set of items that may match t.
+func (ix *TrigramIndex) termCandidates(t *term) ([]int32, bool) {
+	if t.negate || t.typos > 0 || t.kind == termFuzzy || len(t.text) < 3 || !isASCII(t.text) {
+		return nil, false
+	}
+	q := strings.ToLower(t.text)
+	var cands []int32
+	for j := 0; j+2 < len(q); j++ {
+		k := trigramKey(q[j], q[j+1], q[j+2])
+		p := ix.postings[ix.offsets[k]:ix.offsets[k+1]]
+		if j == 0 {
+			cands = p
+		} else {
+			cands = intersectSorted(cands, p)
+		}
+		if len(cands) == 0 {
+			break
+		}
+	}
+	cands = unionSorted(cands, ix.always)
+	if t.acronym && t.kind == termExact {
+		// Exact mode also matches the term as word initials (see
+		// matchAcronym), which needn't contain its trigrams.
+		var acronyms []int32
+		for i, mask := range ix.initials {
+			if mask&t.mask == t.mask {
+				acronyms = append(acronyms, int32(i))
+			}
+		}
+		cands = unionSorted(cands, acronyms)
+	}
+	return cands, true
+}
+
+// intersectSorted returns the items in both ascending lists. It never
+// writes into a or b, which may be posting lists.
+func intersectSorted(a, b []int32) []int32 {
+	out := make([]int32, 0, min(len(a), len(b)))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// unionSorted returns the items in either ascending list, ascending and
+// without duplicates. Like intersectSorted it never writes into a or b.
+func unionSorted(a, b []int32) []int32 {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	out := make([]int32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			out = append(out, a[i])
+			i++
+		case a[i] > b[j]:
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
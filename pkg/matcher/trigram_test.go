@@ -0,0 +1,117 @@
+package matcher
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+func trigramTestItems() []input.Item {
+	items := generateItems(2000)
+	for _, text := range []string{
+		"goose-launcher-daemon",
+		"GooseLauncherDaemon.go",
+		"Café Crème handler",
+		"lib/ünïcode_handler.go",
+		"HANDLER_FACTORY",
+		"ha", // shorter than a trigram
+		"",
+	} {
+		item := input.Item{Text: text, Raw: text, Index: len(items)}
+		item.Init()
+		items = append(items, item)
+	}
+	nth, _ := input.ParseFieldRanges("2")
+	for _, line := range []string{"handler\tdocs/x.md", "docs\tcmd/handler.go", "gld\tgoose-launcher-daemon"} {
+		items = append(items, input.ParseLineWithOptions(line, len(items), input.ParseOptions{
+			Delimiter: input.ParseDelimiter(`\t`),
+			Nth:       nth,
+		}))
+	}
+	return items
+}
+
+// Matching only the candidates must give exactly the results of a scan.
+func TestTrigramIndex_CandidatesAgreeWithScan(t *testing.T) {
+	items := trigramTestItems()
+	ix, err := BuildTrigramIndex(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := []string{
+		"handler", "Handler", "HANDLER", "hand", "han", "ha", "xyz",
+		"gld", "GLD", "gd", "cafe", "café", "ünïcode", "deploy_1",
+		"'handler", "^cmd", ".go$", "^api/v1/endpoint_5.go$",
+		"handler !test", "helper | deploy", "hand | zz", "main 1999",
+	}
+	for _, opts := range []Options{
+		{Case: CaseSmart, Exact: true, Extended: true, Normalize: true},
+		{Case: CaseRespect, Exact: true, Extended: true},
+		{Case: CaseSmart, Extended: true, Normalize: true},
+		{Case: CaseSmart, Exact: true, Extended: true, Typos: 1},
+		{Case: CaseSmart, Exact: true},
+	} {
+		m := NewFuzzyMatcherWithOptions(opts)
+		for _, q := range queries {
+			want := FilterSerial(m, q, items, WantScore)
+			cands, ok := Candidates(m, ix, q)
+			if !ok {
+				continue
+			}
+			got, err := RefineContext(context.Background(), m, q, items, cands, WantScore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+				t.Errorf("%+v: %q: %d hits from %d candidates, want %d", opts, q, len(got), len(cands), len(want))
+			}
+		}
+	}
+}
+
+func TestTrigramIndex_WhichQueriesNarrow(t *testing.T) {
+	items := trigramTestItems()
+	ix, err := BuildTrigramIndex(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exact := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart, Exact: true, Extended: true})
+	fuzzy := NewFuzzyMatcherWithOptions(Options{Case: CaseSmart, Extended: true})
+	tests := []struct {
+		m     Matcher
+		query string
+		want  bool
+	}{
+		{exact, "handler", true},
+		{exact, "ha", false},           // shorter than a trigram
+		{exact, "!handler", false},     // negation can't narrow
+		{exact, "handler !test", true}, // the other group does
+		{exact, "hand | ha", false},    // one alternative can't
+		{fuzzy, "handler", false},      // fuzzy terms needn't be contiguous
+		{fuzzy, "'handler", true},
+		{fuzzy, "^cmd", true},
+		{NewFuzzyMatcherWithOptions(Options{Exact: true, Typos: 1}), "handler", false},
+		{NewFuzzyMatcherWithOptions(Options{Regex: true}), "handler", false},
+		{prefixMatcher{}, "handler", false},
+	}
+	for _, tt := range tests {
+		if _, ok := Candidates(tt.m, ix, tt.query); ok != tt.want {
+			t.Errorf("Candidates(%T, %q) ok = %v, want %v", tt.m, tt.query, ok, tt.want)
+		}
+	}
+
+	cands, _ := Candidates(exact, ix, "handler")
+	if len(cands) >= len(items)/2 {
+		t.Errorf("\"handler\" left %d of %d items as candidates", len(cands), len(items))
+	}
+}
+
+func TestBuildTrigramIndex_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BuildTrigramIndex(ctx, generateItems(10)); err == nil {
+		t.Error("expected an error from a cancelled build")
+	}
+}
@@ -0,0 +1,149 @@
+package matcher
+
+import (
+	"math/bits"
+	"unicode/utf8"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+const (
+	// typoTermLen is how many runes a term needs per typo it may contain:
+	// with --typos=2, "lanucher" (8) may have two but "lanu" only one and
+	// "lan" none — short terms with a typo match nearly everything.
+	typoTermLen = 4
+	// typoPenalty is subtracted from an approximate hit's score per edit.
+	// It dwarfs any alignment score, so approximate hits score below every
+	// exact one (negative) and fewer edits beat more.
+	typoPenalty = 1 << 16
+)
+
+// typoBudget is how many edits a term of n runes may contain under
+// --typos=max.
+func typoBudget(max, n int) int {
+	return min(max, n/typoTermLen)
+}
+
+// matchApprox finds the substring of searchText closest to searchQuery by
+// Damerau-Levenshtein distance (optimal string alignment: insertions,
+// deletions, substitutions and adjacent transpositions) and accepts it
+// within t.typos edits. Every rune of the query missing from the text
+// costs at least one edit, so popcount(t.mask &^ CharMask(text)) bounds the
+// distance from below. Highlights cover the text characters that line up
+// with a query character (matched or transposed). Runs only after the
+// normal match failed, behind a length and character-set prefilter.
+func matchApprox(t *term, text, searchText string, asciiPath, withPositions, withScore bool) (bool, []int, int) {
+	k := t.typos
+	if asciiPath {
+		if len(searchText) < len(t.text)-k || bits.OnesCount64(t.mask&^input.CharMask(searchText)) > k {
+			return false, nil, 0
+		}
+		return approxSearch(t, text, []byte(searchText), []byte(t.text), asciiPath, withPositions, withScore)
+	}
+	if utf8.RuneCountInString(searchText) < utf8.RuneCountInString(t.text)-k || bits.OnesCount64(t.mask&^input.CharMask(searchText)) > k {
+		return false, nil, 0
+	}
+	return approxSearch(t, text, []rune(searchText), []rune(t.text), asciiPath, withPositions, withScore)
+}
+
+func approxSearch[T byte | rune](t *term, text string, s, q []T, asciiPath, withPositions, withScore bool) (bool, []int, int) {
+	dist, end := osaSearch(s, q)
+	if dist > t.typos {
+		return false, nil, 0
+	}
+	if !withPositions && !withScore {
+		return true, nil, 0
+	}
+	positions := osaAlign(s[:end], q)
+	score := -dist * typoPenalty
+	if withScore && len(positions) > 0 {
+		score += scoreFixed(text, asciiPath, positions)
+	}
+	return true, positions, score
+}
+
+// osaSearch returns the smallest edit distance between q and any
+// substring of s, and the end of the first substring achieving it. Only
+// three columns of the matrix are kept (q down, s across, with a free
+// start anywhere in s); it stops early at the first exact hit.
+func osaSearch[T byte | rune](s, q []T) (dist, end int) {
+	m := len(q)
+	prev2 := make([]int, 3*(m+1))
+	prev, cur := prev2[m+1:2*(m+1)], prev2[2*(m+1):]
+	prev2 = prev2[:m+1]
+	for i := range prev {
+		prev[i] = i
+	}
+	dist, end = prev[m], 0
+	for j := 1; j <= len(s); j++ {
+		cur[0] = 0
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if q[i-1] == s[j-1] {
+				cost = 0
+			}
+			d := min(prev[i-1]+cost, prev[i]+1, cur[i-1]+1)
+			if i > 1 && j > 1 && q[i-1] == s[j-2] && q[i-2] == s[j-1] {
+				d = min(d, prev2[i-2]+1)
+			}
+			cur[i] = d
+		}
+		if cur[m] < dist {
+			dist, end = cur[m], j
+			if dist == 0 {
+				break
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return dist, end
+}
+
+// osaAlign aligns q against a suffix of s (the best substring found by
+// osaSearch ends where s does) and returns the indices in s of characters
+// that match a query character, directly or by transposition.
+func osaAlign[T byte | rune](s, q []T) []int {
+	m, n := len(q), len(s)
+	w := n + 1
+	d := make([]int, (m+1)*w)
+	for i := 0; i <= m; i++ {
+		d[i*w] = i
+	}
+	for i := 1; i <= m; i++ {
+		for j := 1; j <= n; j++ {
+			cost := 1
+			if q[i-1] == s[j-1] {
+				cost = 0
+			}
+			v := min(d[(i-1)*w+j-1]+cost, d[(i-1)*w+j]+1, d[i*w+j-1]+1)
+			if i > 1 && j > 1 && q[i-1] == s[j-2] && q[i-2] == s[j-1] {
+				v = min(v, d[(i-2)*w+j-2]+1)
+			}
+			d[i*w+j] = v
+		}
+	}
+
+	positions := make([]int, 0, m)
+	i, j := m, n
+	for i > 0 && j > 0 {
+		v := d[i*w+j]
+		switch {
+		case q[i-1] == s[j-1] && v == d[(i-1)*w+j-1]:
+			positions = append(positions, j-1)
+			i, j = i-1, j-1
+		case i > 1 && j > 1 && q[i-1] == s[j-2] && q[i-2] == s[j-1] && v == d[(i-2)*w+j-2]+1:
+			positions = append(positions, j-1, j-2)
+			i, j = i-2, j-2
+		case v == d[(i-1)*w+j-1]+1:
+			i, j = i-1, j-1 // substitution
+		case v == d[(i-1)*w+j]+1:
+			i-- // query character missing from the text
+		default:
+			j-- // extra text character
+		}
+	}
+	for l, r := 0, len(positions)-1; l < r; l, r = l+1, r-1 {
+		positions[l], positions[r] = positions[r], positions[l]
+	}
+	return positions
+}
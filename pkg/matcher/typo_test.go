@@ -0,0 +1,101 @@
+package matcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOSASearch(t *testing.T) {
+	tests := []struct {
+		text, query string
+		dist, end   int
+	}{
+		{"launcher", "launcher", 0, 8},
+		{"launcher", "lanucher", 1, 8},       // transposition
+		{"launcher", "lauhcner", 2, 8},       // two substitutions
+		{"goose-launcher", "lancher", 1, 14}, // deletion, inside a longer text
+		{"launcher", "laaunncher", 2, 8},
+		{"abc", "xyz", 3, 0},
+	}
+	for _, tt := range tests {
+		dist, end := osaSearch([]byte(tt.text), []byte(tt.query))
+		if dist != tt.dist || end != tt.end {
+			t.Errorf("osaSearch(%q, %q) = %d, %d; want %d, %d", tt.text, tt.query, dist, end, tt.dist, tt.end)
+		}
+	}
+}
+
+func TestTypos_FindsTransposition(t *testing.T) {
+	for _, exact := range []bool{false, true} {
+		m := NewFuzzyMatcherWithOptions(Options{Exact: exact, Typos: 1})
+		ok, positions, score := m.Match("lanucher", initItem("goose-launcher"))
+		if !ok {
+			t.Fatalf("exact=%v: \"lanucher\" should find \"goose-launcher\" with --typos=1", exact)
+		}
+		if want := []int{6, 7, 8, 9, 10, 11, 12, 13}; !reflect.DeepEqual(positions, want) {
+			t.Errorf("exact=%v: positions %v, want %v", exact, positions, want)
+		}
+		if score >= 0 {
+			t.Errorf("exact=%v: approximate hit scored %d, want below every exact hit", exact, score)
+		}
+		if _, _, exactScore := m.Match("launcher", initItem("goose-launcher")); exactScore <= score {
+			t.Errorf("exact=%v: exact hit %d should outscore approximate hit %d", exact, exactScore, score)
+		}
+
+		if ok, _, _ := NewFuzzyMatcherWithOptions(Options{Exact: exact}).Match("lanucher", initItem("launcher")); ok {
+			t.Errorf("exact=%v: without --typos the typo must not match", exact)
+		}
+	}
+}
+
+func TestTypos_Budget(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Exact: true, Typos: 2})
+	tests := []struct {
+		query, text string
+		want        bool
+	}{
+		{"lun", "launcher", false},       // 3 runes: no typos allowed
+		{"lanch", "launcher", true},      // 5 runes: one
+		{"lnchr", "launcher", false},     // 5 runes: three deletions needed
+		{"luncher", "launcher", true},    // 7 runes: one
+		{"lanuchre", "launcher", true},   // 8 runes: two
+		{"lanuchrex", "launcher", false}, // three edits
+		{"zzzzzzzz", "launcher", false},  // fails the character prefilter
+	}
+	for _, tt := range tests {
+		if ok, _, _ := m.Match(tt.query, initItem(tt.text)); ok != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.query, tt.text, ok, tt.want)
+		}
+	}
+}
+
+func TestTypos_ExtendedOperators(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Exact: true, Extended: true, Typos: 1})
+	if ok, _, _ := m.Match("main lanucher", initItem("main launcher")); !ok {
+		t.Error("a misspelled AND term should still match")
+	}
+	if ok, _, _ := m.Match("main !lanucher", initItem("main launcher")); !ok {
+		t.Error("negated terms must match literally: launcher doesn't contain lanucher")
+	}
+	if ok, _, _ := m.Match("^lanucher", initItem("launcher")); ok {
+		t.Error("anchored terms must match literally")
+	}
+}
+
+func TestTypos_NonASCII(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Exact: true, Typos: 1})
+	ok, positions, _ := m.Match("éocle", initItem("l'école"))
+	if !ok || !reflect.DeepEqual(positions, []int{2, 3, 4, 5, 6}) {
+		t.Errorf("Match = %v %v, want the transposed run [2 3 4 5 6]", ok, positions)
+	}
+}
+
+func TestNarrows_TypoBudget(t *testing.T) {
+	m := NewFuzzyMatcherWithOptions(Options{Typos: 2})
+	if Narrows(m, "lau", "laun") {
+		t.Error("laun may contain a typo where lau may not, so it can match more")
+	}
+	if !Narrows(m, "laun", "launc") {
+		t.Error("same typo budget: appending narrows")
+	}
+}
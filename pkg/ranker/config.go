@@ -0,0 +1,118 @@
+package ranker
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// weights maps the names --weights and ranker config files use to the
+// weight fields they set.
+var weights = map[string]func(r *Ranker) *float64{
+	"compactness":  func(r *Ranker) *float64 { return &r.CompactnessWeight },
+	"early":        func(r *Ranker) *float64 { return &r.EarlyMatchWeight },
+	"consecutive":  func(r *Ranker) *float64 { return &r.ConsecutiveWeight },
+	"length-ratio": func(r *Ranker) *float64 { return &r.LengthRatioWeight },
+	"position":     func(r *Ranker) *float64 { return &r.OriginalPosWeight },
+	"basename":     func(r *Ranker) *float64 { return &r.BasenameWeight },
+	"separator":    func(r *Ranker) *float64 { return &r.SeparatorWeight },
+	"acronym":      func(r *Ranker) *float64 { return &r.AcronymWeight },
+	"history":      func(r *Ranker) *float64 { return &r.HistoryWeight },
+	"learned":      func(r *Ranker) *float64 { return &r.LearnedWeight },
+	"priority":     func(r *Ranker) *float64 { return &r.PriorityWeight },
+}
+
+// positionSpan names OriginalPosSpan, the one integer setting.
+const positionSpan = "position-span"
+
+// WeightNames lists the settings Set accepts besides "tiebreak", sorted.
+func WeightNames() []string {
+	names := []string{positionSpan}
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set overrides one setting by name: a weight, "position-span" or
+// "tiebreak" (a ParseTiebreak chain).
+func (r *Ranker) Set(name, value string) error {
+	switch name {
+	case "tiebreak":
+		chain, err := ParseTiebreak(value)
+		if err != nil {
+			return err
+		}
+		r.Tiebreak = chain
+		return nil
+	case positionSpan:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q (want a count of items)", name, value)
+		}
+		r.OriginalPosSpan = n
+		return nil
+	}
+	field, ok := weights[name]
+	if !ok {
+		return fmt.Errorf("unknown ranker setting %q (available: tiebreak, %s)", name, strings.Join(WeightNames(), ", "))
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s weight %q", name, value)
+	}
+	*field(r) = v
+	return nil
+}
+
+// SetWeights applies a comma-separated list of name=value weights, the
+// form --weights takes: "compactness=40,early=20".
+func (r *Ranker) SetWeights(spec string) error {
+	for _, kv := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("invalid weight %q (want name=value)", kv)
+		}
+		if err := r.Set(strings.TrimSpace(name), strings.TrimSpace(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadConfig applies a ranker config file: one "name = value" setting per
+// line, as Set takes them, with blank lines and # comments ignored.
+//
+//	# ranking for the files plugin
+//	basename = 30
+//	position-span = 500
+//	tiebreak = length,begin
+func (r *Ranker) LoadConfig(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ranker config: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: want name = value", path, line)
+		}
+		if err := r.Set(strings.TrimSpace(name), strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ranker config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package ranker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation is a match's score taken apart (--explain): each component
+// already multiplied by its weight, so they add up to the score ranking
+// sorts by. Field names follow the weight names --weights takes.
+type Explanation struct {
+	Compactness float64
+	EarlyMatch  float64
+	Consecutive float64
+	LengthRatio float64
+	OriginalPos float64
+	Basename    float64
+	Separator   float64
+	Acronym     float64
+	History     float64
+	Learned     float64
+	Priority    float64
+}
+
+// Explain scores m for query as RankMatches would, component by component.
+func (r *Ranker) Explain(query string, m Match) Explanation {
+	e := r.components(query, m.Item.Text, m.Positions, m.Item.Index)
+	e.History = r.historyScore(m.Item)
+	e.Priority = r.priorityScore(m.Item)
+	if r.Learned != nil {
+		e.Learned = r.Learned.Picks(query)[m.Item.Raw] * r.LearnedWeight
+	}
+	return e
+}
+
+// matchScore sums the components scoreMatch covers, in its order, so the
+// result is bit-for-bit the same.
+func (e Explanation) matchScore() float64 {
+	score := 0.0
+	for _, v := range []float64{e.Compactness, e.EarlyMatch, e.Consecutive, e.LengthRatio, e.OriginalPos, e.Basename, e.Separator, e.Acronym} {
+		score += v
+	}
+	return score
+}
+
+// Total is the full score: the match components plus the history,
+// priority and learned boosts.
+func (e Explanation) Total() float64 {
+	return e.matchScore() + e.History + e.Priority + e.Learned
+}
+
+// String formats e on one line, total first: "score=96.31 compactness=35.00
+// early=25.00 ...". The five match components always appear; the path,
+// acronym, boost and priority components only when they contributed.
+func (e Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "score=%.2f compactness=%.2f early=%.2f consecutive=%.2f length-ratio=%.2f position=%.2f",
+		e.Total(), e.Compactness, e.EarlyMatch, e.Consecutive, e.LengthRatio, e.OriginalPos)
+	for _, c := range []struct {
+		name  string
+		value float64
+	}{
+		{"basename", e.Basename},
+		{"separator", e.Separator},
+		{"acronym", e.Acronym},
+		{"history", e.History},
+		{"learned", e.Learned},
+		{"priority", e.Priority},
+	} {
+		if c.value != 0 {
+			fmt.Fprintf(&b, " %s=%.2f", c.name, c.value)
+		}
+	}
+	return b.String()
+}
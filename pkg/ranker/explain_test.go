@@ -0,0 +1,37 @@
+package ranker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// The explained components add up to the score the match is ranked by.
+func TestExplain_AddsUpToScore(t *testing.T) {
+	r := NewPathRanker()
+	r.History = historyMap{"src/cmd/main.go": 0.5}
+	r.Learned = learnedMap{"main": {"src/cmd/main.go": 1}}
+	var matches []Match
+	for i, text := range []string{"src/cmd/main.go", "domain/x.go", "README"} {
+		matches = append(matches, Match{Item: input.Item{Text: text, Raw: text, Index: i}, Positions: []int{0, 1, 2, 3}})
+	}
+	ranked := r.RankMatches(matches, "main")
+	for _, m := range ranked {
+		e := r.Explain("main", Match{Item: m.Item, Positions: m.Positions})
+		if e.Total() != m.Score {
+			t.Errorf("%s: explained total %v, ranked with %v", m.Item.Text, e.Total(), m.Score)
+		}
+	}
+
+	e := r.Explain("main", matches[0])
+	got := e.String()
+	for _, part := range []string{"score=", " compactness=", " early=", " consecutive=", " length-ratio=", " position=", " history=25.00", " learned=1000.00"} {
+		if !strings.Contains(got, part) {
+			t.Errorf("String() = %q, missing %q", got, part)
+		}
+	}
+	if strings.Contains(got, "\t") {
+		t.Errorf("String() = %q, must stay one tab-free column", got)
+	}
+}
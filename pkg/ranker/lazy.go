@@ -0,0 +1,296 @@
+package ranker
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Ranking is a ranking sorted on demand. A window shows a few dozen rows
+// and users rarely scroll past a few hundred, so RankLazy scores every
+// match (linear) but only selects the first rows in order; Extend sorts
+// further as the user pages down. Any prefix it returns is exactly the
+// prefix of RankMatches' full sort.
+//
+// A Ranking is safe for concurrent use; the prefix Extend returns never
+// changes afterwards.
+type Ranking struct {
+	r  *Ranker
+	mu sync.Mutex
+	// keys[:len(order)] are the matches ranked so far, in order; the rest
+	// form a binary heap stored back to front, best match last, so each
+	// pop moves the best match to the front of the heap's slot range
+	// without shifting anything.
+	keys  []sortKey
+	order []int32 // InputIndex of each ranked match; capacity len(keys)
+	// parts, in a ranking built by Merge, are the rankings it merges, in
+	// input order. keys then holds only the ranked prefix, and ranking
+	// further pops whichever part's next match sorts first.
+	parts []rankPart
+	n     int // number of matches
+}
+
+// rankPart is one of a merged ranking's parts: a ranking from RankLazy or
+// RankPriority, which may be shared with other merged rankings.
+type rankPart struct {
+	rk     *Ranking
+	offset int32 // added to rk's input indices
+	taken  int   // rk's ranked matches already in the merged prefix
+}
+
+// RankLazy scores matches and ranks the first k of them.
+func (r *Ranker) RankLazy(matches []Match, query string, k int) *Ranking {
+	keys := make([]sortKey, len(matches))
+	var picks map[string]float64
+	if r.Learned != nil {
+		picks = r.Learned.Picks(query)
+	}
+	spans := r.comparesSpans()
+	score := func(start, end int) {
+		for i := start; i < end; i++ {
+			m := &matches[i]
+			keys[i] = sortKey{score: r.score(query, m, picks), matcherScore: m.Score, index: int32(i)}
+			if spans {
+				keys[i].length, keys[i].begin, keys[i].end = spanOf(m.Item, m.Positions)
+			}
+		}
+	}
+
+	workers := min(runtime.GOMAXPROCS(0), len(matches)/minShardSize)
+	if workers < 2 || len(matches) < parallelThreshold {
+		score(0, len(matches))
+	} else {
+		size := (len(matches) + workers - 1) / workers
+		var wg sync.WaitGroup
+		for start := 0; start < len(matches); start += size {
+			wg.Add(1)
+			go func(start, end int) {
+				defer wg.Done()
+				score(start, end)
+			}(start, min(start+size, len(matches)))
+		}
+		wg.Wait()
+	}
+	return r.newRanking(keys, k)
+}
+
+// RankPriority orders matches by priority alone (Item.Priority times
+// PriorityWeight), input order breaking ties, and ranks the first k. It's
+// how producer priorities count when results are filtered, not ranked:
+// without priorities the order is the input's. Approximate matches
+// (negative Score) still come after every exact one, as when ranked.
+func (r *Ranker) RankPriority(matches []Match, k int) *Ranking {
+	keys := make([]sortKey, len(matches))
+	for i := range matches {
+		keys[i] = sortKey{score: r.priorityScore(matches[i].Item), index: int32(i)}
+		if matches[i].Score < 0 {
+			keys[i].matcherScore = -1 // only the sign counts, not the alignment
+		}
+	}
+	// A bare ranker's chain: score, then input order.
+	return (&Ranker{}).newRanking(keys, k)
+}
+
+// newRanking heapifies keys, which it takes ownership of, and ranks the
+// first k.
+func (r *Ranker) newRanking(keys []sortKey, k int) *Ranking {
+	rk := &Ranking{r: r, keys: keys, order: make([]int32, 0, len(keys)), n: len(keys)}
+	for j := len(keys)/2 - 1; j >= 0; j-- {
+		rk.siftDown(j)
+	}
+	rk.extend(k)
+	return rk
+}
+
+// Merge returns a ranking of rk's matches followed by more's, whose input
+// indices it shifts past rk's, with the first k ranked, and at least as
+// many as rk has ranked. It lets a streamed-in batch join a ranking
+// without touching what nobody looks at: the two ranked prefixes are
+// merged, and the rest of each stays in its heap until Extend needs it.
+// rk doesn't change; more, a ranking from RankLazy or RankPriority,
+// becomes part of the result.
+//
+// The result keeps O(log n) parts by combining a part with the one before
+// it once it's as large, like a binary counter, so across a whole stream
+// each match is copied O(log n) times.
+func (rk *Ranking) Merge(more *Ranking, k int) *Ranking {
+	rk.mu.Lock()
+	prefix := rk.keys[:len(rk.order)] // never changes
+	parts := []rankPart{{rk: rk}}
+	if rk.parts != nil {
+		parts = make([]rankPart, len(rk.parts), len(rk.parts)+1)
+		for i, p := range rk.parts {
+			parts[i] = rankPart{rk: p.rk, offset: p.offset}
+		}
+	}
+	rk.mu.Unlock()
+
+	m := &Ranking{r: rk.r, n: rk.n + more.n}
+	m.parts = append(parts, rankPart{rk: more, offset: int32(rk.n)})
+	last := &m.parts[len(m.parts)-1]
+	k = min(max(k, len(prefix)), m.n)
+	m.keys = make([]sortKey, 0, k)
+	m.order = make([]int32, 0, k)
+	used := 0 // of prefix
+	for len(m.order) < k {
+		if used == len(prefix) && len(prefix) < rk.n {
+			break // rk's next match isn't ranked, and ranking it would change rk
+		}
+		next, ok := last.head()
+		if used < len(prefix) && (!ok || m.r.keyBefore(&prefix[used], &next)) {
+			next = prefix[used]
+			used++
+		} else {
+			last.taken++
+		}
+		m.keys = append(m.keys, next)
+		m.order = append(m.order, next.index)
+	}
+	// Credit the matches taken from rk's prefix to the parts they came from.
+	for _, key := range prefix[:used] {
+		p := sort.Search(len(parts), func(p int) bool { return parts[p].offset > key.index }) - 1
+		m.parts[p].taken++
+	}
+
+	for len(m.parts) > 1 {
+		a, b := m.parts[len(m.parts)-2], m.parts[len(m.parts)-1]
+		if b.rk.n < a.rk.n {
+			break
+		}
+		m.parts = m.parts[:len(m.parts)-1]
+		m.parts[len(m.parts)-1] = m.r.combine(a, b)
+	}
+	return m
+}
+
+// combine builds a single part holding a's matches then b's, which
+// follows it, ranked as far as the two were taken.
+func (r *Ranker) combine(a, b rankPart) rankPart {
+	keys := make([]sortKey, 0, a.rk.n+b.rk.n)
+	a.rk.mu.Lock()
+	keys = append(keys, a.rk.keys...)
+	a.rk.mu.Unlock()
+	shift := b.offset - a.offset
+	b.rk.mu.Lock()
+	for _, key := range b.rk.keys {
+		key.index += shift
+		keys = append(keys, key)
+	}
+	b.rk.mu.Unlock()
+	taken := a.taken + b.taken
+	return rankPart{rk: r.newRanking(keys, taken), offset: a.offset, taken: taken}
+}
+
+// head returns the best of p's matches not yet taken, ranking it if need
+// be, with its input index shifted by the offset.
+func (p *rankPart) head() (sortKey, bool) {
+	p.rk.mu.Lock()
+	defer p.rk.mu.Unlock()
+	if p.taken >= p.rk.n {
+		return sortKey{}, false
+	}
+	p.rk.extend(p.taken + 1)
+	key := p.rk.keys[p.taken]
+	key.index += p.offset
+	return key, true
+}
+
+// Len is the number of matches.
+func (rk *Ranking) Len() int {
+	return rk.n // never changes; no lock needed
+}
+
+// Sorted is the number of matches ranked so far.
+func (rk *Ranking) Sorted() int {
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+	return len(rk.order)
+}
+
+// Extend ranks the first n matches (all of them once n reaches Len) and
+// returns the input indices of every match ranked so far, in order.
+func (rk *Ranking) Extend(n int) []int32 {
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+	rk.extend(n)
+	return rk.order
+}
+
+// Order appends to dst the input index of every match in display order:
+// the ranked prefix, then the others in no particular order.
+func (rk *Ranking) Order(dst []int32) []int32 {
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+	if rk.parts == nil {
+		return rk.appendFrom(dst, 0, 0)
+	}
+	dst = append(dst, rk.order...)
+	for _, p := range rk.parts {
+		p.rk.mu.Lock()
+		dst = p.rk.appendFrom(dst, p.taken, p.offset)
+		p.rk.mu.Unlock()
+	}
+	return dst
+}
+
+// appendFrom appends the input indices of a heap ranking's matches, in
+// display order from row from on, shifted by offset. Caller holds mu.
+func (rk *Ranking) appendFrom(dst []int32, from int, offset int32) []int32 {
+	for _, idx := range rk.order[from:] {
+		dst = append(dst, idx+offset)
+	}
+	for i := len(rk.keys) - 1; i >= len(rk.order); i-- {
+		dst = append(dst, rk.keys[i].index+offset)
+	}
+	return dst
+}
+
+// extend pops the heap, or the parts, until n matches are ranked. Caller
+// holds mu.
+func (rk *Ranking) extend(n int) {
+	n = min(n, rk.n)
+	if rk.parts != nil {
+		for len(rk.order) < n {
+			best := -1
+			var bestKey sortKey
+			for p := range rk.parts {
+				key, ok := rk.parts[p].head()
+				if ok && (best < 0 || rk.r.keyBefore(&key, &bestKey)) {
+					best, bestKey = p, key
+				}
+			}
+			rk.parts[best].taken++
+			rk.keys = append(rk.keys, bestKey)
+			rk.order = append(rk.order, bestKey.index)
+		}
+		return
+	}
+	for len(rk.order) < n {
+		// Heap slot j lives at keys[len(keys)-1-j]: the root at the end, the
+		// last slot at the first unranked position. Swapping them and
+		// shrinking the heap by one ranks the root there.
+		first, root := len(rk.order), len(rk.keys)-1
+		rk.keys[first], rk.keys[root] = rk.keys[root], rk.keys[first]
+		rk.order = append(rk.order, rk.keys[first].index)
+		rk.siftDown(0)
+	}
+}
+
+// siftDown restores the heap below slot j.
+func (rk *Ranking) siftDown(j int) {
+	size := len(rk.keys) - len(rk.order)
+	last := len(rk.keys) - 1
+	for {
+		best := j
+		for _, child := range [2]int{2*j + 1, 2*j + 2} {
+			if child < size && rk.r.keyBefore(&rk.keys[last-child], &rk.keys[last-best]) {
+				best = child
+			}
+		}
+		if best == j {
+			return
+		}
+		rk.keys[last-j], rk.keys[last-best] = rk.keys[last-best], rk.keys[last-j]
+		j = best
+	}
+}
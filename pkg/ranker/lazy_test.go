@@ -0,0 +1,166 @@
+package ranker
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// randomMatches builds n matches with plenty of exact score ties, so the
+// tiebreaks decide much of the order.
+func randomMatches(n int, seed int64) []Match {
+	rng := rand.New(rand.NewSource(seed))
+	matches := make([]Match, n)
+	for i := range matches {
+		start := rng.Intn(8)
+		text := fmt.Sprintf("%s/tree%d.go", "src/pkg/internal"[:rng.Intn(16)], rng.Intn(50))
+		matches[i] = Match{
+			Item:      input.Item{Text: text, Raw: text, Index: rng.Intn(n), ASCII: true},
+			Positions: []int{start, start + 1, start + 2 + rng.Intn(2)},
+			Score:     rng.Intn(5) - 1, // some approximate hits
+		}
+	}
+	return matches
+}
+
+func TestRankLazy_MatchesFullSort(t *testing.T) {
+	matches := randomMatches(3000, 1)
+	for _, spec := range []string{"", "score", "length", "begin,end", "end,length", "index"} {
+		r := NewRanker()
+		if spec != "" {
+			chain, err := ParseTiebreak(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Tiebreak = chain
+		}
+		full := r.RankMatches(matches, "tre")
+
+		rk := r.RankLazy(matches, "tre", 30)
+		if rk.Len() != len(matches) || rk.Sorted() != 30 {
+			t.Fatalf("tiebreak %q: Len %d, Sorted %d", spec, rk.Len(), rk.Sorted())
+		}
+		prefix := rk.Extend(0)
+		for _, n := range []int{30, 31, 500, len(matches) + 10} {
+			prefix = rk.Extend(n)
+		}
+		if len(prefix) != len(matches) {
+			t.Fatalf("tiebreak %q: %d ranked after extending past the end", spec, len(prefix))
+		}
+		for i := range full {
+			if int(prefix[i]) != full[i].InputIndex {
+				t.Fatalf("tiebreak %q: row %d is match %d, full sort has %d", spec, i, prefix[i], full[i].InputIndex)
+			}
+		}
+	}
+}
+
+func TestRanking_OrderAndMerge(t *testing.T) {
+	matches := randomMatches(2000, 2)
+	r := NewRanker()
+	full := r.RankMatches(matches, "tre")
+
+	rk := r.RankLazy(matches, "tre", 10)
+	order := rk.Order(nil)
+	seen := make([]bool, len(matches))
+	for i, idx := range order {
+		if i < 10 && int(idx) != full[i].InputIndex {
+			t.Errorf("Order row %d = %d, want %d", i, idx, full[i].InputIndex)
+		}
+		seen[idx] = true
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Fatalf("Order is missing match %d", i)
+		}
+	}
+
+	// Ranking a stream in two batches and merging equals ranking it whole.
+	head := r.RankLazy(matches[:1200], "tre", 5)
+	head.Extend(50) // a partly ranked base
+	merged := head.Merge(r.RankLazy(matches[1200:], "tre", 0), 20)
+	if head.Len() != 1200 || head.Sorted() != 50 {
+		t.Errorf("Merge changed its input: Len %d, Sorted %d", head.Len(), head.Sorted())
+	}
+	if merged.Sorted() != 50 {
+		t.Errorf("merged Sorted %d, want the base's 50", merged.Sorted())
+	}
+	got := merged.Extend(len(matches))
+	for i := range full {
+		if int(got[i]) != full[i].InputIndex {
+			t.Fatalf("merged row %d = %d, want %d", i, got[i], full[i].InputIndex)
+		}
+	}
+}
+
+// A stream merged in many batches ranks like a full sort at every step,
+// without ranking the earlier rankings any further, and keeps few parts.
+func TestRanking_MergeStream(t *testing.T) {
+	matches := randomMatches(5000, 4)
+	r := NewRanker()
+	rk := r.RankLazy(matches[:1000], "tre", 20)
+	for n := 1000; n < len(matches); {
+		batch := min(37+n%200, len(matches)-n)
+		prev, sorted := rk, rk.Sorted()
+		rk = prev.Merge(r.RankLazy(matches[n:n+batch], "tre", 0), 20)
+		n += batch
+		if prev.Sorted() != sorted {
+			t.Fatalf("at %d: Merge ranked its input further", n)
+		}
+		if rk.Len() != n || rk.Sorted() < sorted {
+			t.Fatalf("at %d: Len %d, Sorted %d (was %d)", n, rk.Len(), rk.Sorted(), sorted)
+		}
+		full := r.RankMatches(matches[:n], "tre")
+		for i, idx := range rk.Extend(0) {
+			if int(idx) != full[i].InputIndex {
+				t.Fatalf("at %d: row %d = %d, want %d", n, i, idx, full[i].InputIndex)
+			}
+		}
+		if n%7 == 0 {
+			rk.Extend(rk.Sorted() + 100) // the user scrolls
+		}
+		if len(rk.parts) > 12 {
+			t.Fatalf("at %d: %d parts", n, len(rk.parts))
+		}
+	}
+
+	order := rk.Order(nil)
+	seen := make([]bool, len(matches))
+	for _, idx := range order {
+		if seen[idx] {
+			t.Fatalf("Order lists match %d twice", idx)
+		}
+		seen[idx] = true
+	}
+	if len(order) != len(matches) {
+		t.Fatalf("Order has %d matches, want %d", len(order), len(matches))
+	}
+	full := r.RankMatches(matches, "tre")
+	got := rk.Extend(len(matches))
+	for i := range full {
+		if int(got[i]) != full[i].InputIndex {
+			t.Fatalf("row %d = %d, want %d", i, got[i], full[i].InputIndex)
+		}
+	}
+}
+
+func BenchmarkRankMatches_500k(b *testing.B) {
+	matches := randomMatches(500000, 3)
+	r := NewRanker()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.RankMatches(matches, "tre")
+	}
+}
+
+// The first screen and a page of scrolling: what the window asks for.
+func BenchmarkRankLazy_500k(b *testing.B) {
+	matches := randomMatches(500000, 3)
+	r := NewRanker()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.RankLazy(matches, "tre", 256)
+	}
+}
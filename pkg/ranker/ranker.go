@@ -1,19 +1,42 @@
 package ranker
 
 import (
-	"sort"
+	"runtime"
+	"slices"
+	"sync"
 
 	"github.com/sam33r/goose-launcher/pkg/input"
 )
 
+// Match is one matcher hit handed to the ranker: the item, the highlight
+// positions and the matcher's own score (0 if it doesn't score).
+type Match struct {
+	Item      input.Item
+	Positions []int
+	Score     int
+}
+
 // MatchScore represents a scored match result
 type MatchScore struct {
 	Item            input.Item
 	Score           float64
 	Positions       []int
 	OriginalIndex   int // Position in original input list
+	MatcherScore    int // Score reported by the matcher; breaks ties in Score
+	InputIndex      int // Position in the RankMatches input; final tiebreak
 }
 
+const (
+	// parallelThreshold is the match count from which RankMatches scores
+	// and sorts shards on GOMAXPROCS goroutines and merges them. Below it
+	// the fan-out costs more than it saves.
+	parallelThreshold = 10000
+	// minShardSize caps the worker count so each shard is worth a goroutine.
+	minShardSize = 2500
+	// defaultPosSpan is OriginalPosSpan when unset.
+	defaultPosSpan = 10000
+)
+
 // Ranker handles scoring and ranking of matched items
 type Ranker struct {
 	// Weights for different scoring components (sum to 100)
@@ -22,9 +45,59 @@ type Ranker struct {
 	ConsecutiveWeight   float64 // Bonus for consecutive character matches
 	LengthRatioWeight   float64 // Query length vs text length
 	OriginalPosWeight   float64 // Preference for items earlier in original list
+	BasenameWeight      float64 // Matched chars in the last path component
+	SeparatorWeight     float64 // Matched chars starting a path component
+	OriginalPosSpan     int     // Items over which OriginalPosWeight decays to 0; 0 means 10000
+
+	// Paths measures the early-match bonus from the start of the path
+	// component the match begins in, not from the start of the text, so
+	// "main" is as early in "cmd/app/main.go" as in "main.go".
+	Paths bool
+
+	// AcronymWeight is added on top when every matched char starts a word
+	// of the text ("gld" → goose-launcher-daemon, "fb" → FooBar.go). It
+	// outweighs the spread-out positions that cost such hits compactness,
+	// so intentional initialisms rank above incidental substrings.
+	AcronymWeight float64
+
+	// History, when set, adds HistoryWeight times the item's frecency, so
+	// the items the user picks often and lately rank above equally good
+	// matches (--history-rank).
+	History       History
+	HistoryWeight float64
+
+	// Learned, when set, adds LearnedWeight times the boost it learned
+	// for the query and item. The weight outscores every other component,
+	// so the user's usual pick for a query ranks first.
+	Learned       Learned
+	LearnedWeight float64
+
+	// PriorityWeight scales the priority producers give items (see
+	// input.Item.Priority), so a running app or a pinned bookmark ranks
+	// above equally good matches. Unlike the other weights it also orders
+	// results without --rank (see RankPriority).
+	PriorityWeight float64
+
+	// Tiebreak is the chain ordering matches (see ParseTiebreak); nil
+	// orders by score alone. Input order settles any tie left.
+	Tiebreak []Criterion
+}
+
+// History reports how strongly the user has preferred an item in past
+// selections, from 0 (never chosen) to 1 (their favourite).
+type History interface {
+	Frecency(item input.Item) float64
+}
+
+// Learned remembers which items the user chose for a query before.
+type Learned interface {
+	// Picks returns the boost, from 0 to 1, of each item (by Raw) chosen
+	// for query; nil when there's none.
+	Picks(query string) map[string]float64
 }
 
-// NewRanker creates a ranker with default weights
+// NewRanker creates a ranker with default weights: the "default" scheme,
+// which treats the text as flat
 func NewRanker() *Ranker {
 	return &Ranker{
 		CompactnessWeight:   35.0,
@@ -32,56 +105,190 @@ func NewRanker() *Ranker {
 		ConsecutiveWeight:   20.0,
 		LengthRatioWeight:   10.0,
 		OriginalPosWeight:   10.0,
+		AcronymWeight:       60.0,
+		HistoryWeight:       50.0,
+		LearnedWeight:       1000.0,
+		PriorityWeight:      10.0,
 	}
 }
 
 // RankMatches scores and sorts matched items by relevance
 // Returns sorted slice of MatchScore
-func (r *Ranker) RankMatches(items []input.Item, positions map[int][]int, query string) []MatchScore {
-	if len(items) == 0 {
+//
+// Ties on Score fall back to the Tiebreak chain (by default the matcher's
+// score) and then to input order, so the result is fully determined by the
+// input; the sort is stable besides. That lets large inputs
+// be ranked as independently sorted shards merged by score, with output
+// identical to a single sort.
+func (r *Ranker) RankMatches(matches []Match, query string) []MatchScore {
+	if len(matches) == 0 {
 		return nil
 	}
 
-	scores := make([]MatchScore, 0, len(items))
+	scores := make([]MatchScore, len(matches))
+	var picks map[string]float64
+	if r.Learned != nil {
+		picks = r.Learned.Picks(query)
+	}
 
-	for i, item := range items {
-		pos := positions[i]
-		score := r.scoreMatch(query, item.Text, pos, item.Index)
+	workers := min(runtime.GOMAXPROCS(0), len(matches)/minShardSize)
+	if workers < 2 || len(matches) < parallelThreshold {
+		r.rankShard(scores, matches, 0, query, picks)
+		return scores
+	}
 
-		scores = append(scores, MatchScore{
-			Item:          item,
-			Score:         score,
-			Positions:     pos,
-			OriginalIndex: item.Index,
-		})
+	size := (len(matches) + workers - 1) / workers
+	var shards [][]MatchScore
+	var wg sync.WaitGroup
+	for start := 0; start < len(matches); start += size {
+		end := min(start+size, len(matches))
+		shard := scores[start:end]
+		shards = append(shards, shard)
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			r.rankShard(shard, matches[start:end], start, query, picks)
+		}(start, end)
 	}
+	wg.Wait()
 
-	// Sort by score (descending)
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].Score > scores[j].Score
+	return r.mergeShards(shards, len(matches))
+}
+
+// rankShard scores matches into dst (same length) and sorts it. offset is
+// the shard's position in the full input, used for the order tiebreak;
+// picks are the query's learned boosts.
+func (r *Ranker) rankShard(dst []MatchScore, matches []Match, offset int, query string, picks map[string]float64) {
+	for i, m := range matches {
+		dst[i] = MatchScore{
+			Item:          m.Item,
+			Score:         r.score(query, &m, picks),
+			Positions:     m.Positions,
+			OriginalIndex: m.Item.Index,
+			MatcherScore:  m.Score,
+			InputIndex:    offset + i,
+		}
+	}
+	// Sort compact keys rather than the large MatchScores, then move each
+	// MatchScore once. The chain ends in input order, so every order is
+	// total; the sort is stable regardless.
+	keys := make([]sortKey, len(dst))
+	for i := range dst {
+		keys[i] = r.keyOf(&dst[i])
+		keys[i].index = int32(i)
+	}
+	slices.SortStableFunc(keys, func(a, b sortKey) int {
+		return r.keyCompare(&a, &b)
 	})
+	permute(dst, keys)
+}
 
-	return scores
+// permute reorders dst so dst[p] becomes the old dst[keys[p].index],
+// following each cycle of the permutation once.
+func permute(dst []MatchScore, keys []sortKey) {
+	for start := range keys {
+		if keys[start].index < 0 {
+			continue
+		}
+		tmp := dst[start]
+		cur := start
+		for {
+			src := int(keys[cur].index)
+			keys[cur].index = -1
+			if src == start {
+				dst[cur] = tmp
+				break
+			}
+			dst[cur] = dst[src]
+			cur = src
+		}
+	}
+}
+
+// RankedBefore reports whether a sorts before b under the default chain
+// of RankMatches: exact hits before approximate ones (a negative matcher
+// score, e.g. --typos), then score descending, then the matcher's score
+// (which also orders matchers that report no positions), then input
+// order. Rankers with a Tiebreak chain order by their Before instead.
+func RankedBefore(a, b *MatchScore) bool {
+	if approxA, approxB := a.MatcherScore < 0, b.MatcherScore < 0; approxA != approxB {
+		return approxB
+	}
+	return scoreThenIndex(a, b)
+}
+
+// mergeShards merges individually sorted shards. The shard count is
+// GOMAXPROCS-sized, so a linear scan of the heads beats a heap.
+func (r *Ranker) mergeShards(shards [][]MatchScore, total int) []MatchScore {
+	out := make([]MatchScore, 0, total)
+	heads := make([]int, len(shards))
+	for len(out) < total {
+		best := -1
+		for s, shard := range shards {
+			if heads[s] == len(shard) {
+				continue
+			}
+			if best < 0 || r.Before(&shard[heads[s]], &shards[best][heads[best]]) {
+				best = s
+			}
+		}
+		out = append(out, shards[best][heads[best]])
+		heads[best]++
+	}
+	return out
+}
+
+// score is m's full score: the match components plus the boosts from
+// selection history, learned picks and the producer's priority.
+func (r *Ranker) score(query string, m *Match, picks map[string]float64) float64 {
+	score := r.scoreMatch(query, m.Item.Text, m.Positions, m.Item.Index) + r.historyScore(m.Item) + r.priorityScore(m.Item)
+	if picks != nil {
+		score += picks[m.Item.Raw] * r.LearnedWeight
+	}
+	return score
+}
+
+// historyScore is the frecency component. It doesn't depend on the match,
+// so it also lifts hits from matchers that report no positions.
+func (r *Ranker) historyScore(item input.Item) float64 {
+	if r.History == nil {
+		return 0
+	}
+	return r.History.Frecency(item) * r.HistoryWeight
+}
+
+// priorityScore is the producer's priority component.
+func (r *Ranker) priorityScore(item input.Item) float64 {
+	return item.Priority * r.PriorityWeight
 }
 
 // scoreMatch calculates a relevance score for a match
 func (r *Ranker) scoreMatch(query, text string, positions []int, originalIndex int) float64 {
+	e := r.components(query, text, positions, originalIndex)
+	return e.matchScore()
+}
+
+// components computes the weighted parts of scoreMatch, which Explain
+// reports one by one.
+func (r *Ranker) components(query, text string, positions []int, originalIndex int) (e Explanation) {
 	if len(positions) == 0 {
-		return 0.0
+		return e
 	}
 
-	score := 0.0
-
 	// 1. Compactness: How tightly grouped are the matches?
 	// Consecutive matches get maximum score, spread out matches get penalized
 	span := positions[len(positions)-1] - positions[0] + 1
 	compactness := float64(len(query)) / float64(span)
-	score += compactness * r.CompactnessWeight
+	e.Compactness = compactness * r.CompactnessWeight
 
 	// 2. Early match bonus: Matches at the start of text rank higher
 	// Position 0 gets full bonus, later positions get diminishing returns
-	earlyBonus := 1.0 / float64(positions[0]+1)
-	score += earlyBonus * r.EarlyMatchWeight
+	start := positions[0]
+	if r.Paths {
+		start -= componentOffset(text, start)
+	}
+	earlyBonus := 1.0 / float64(start+1)
+	e.EarlyMatch = earlyBonus * r.EarlyMatchWeight
 
 	// 3. Consecutive match bonus: Reward exact substring matches
 	consecutive := 0
@@ -94,23 +301,40 @@ func (r *Ranker) scoreMatch(query, text string, positions []int, originalIndex i
 	if len(positions) > 1 {
 		consecutiveRatio = float64(consecutive) / float64(len(positions)-1)
 	}
-	score += consecutiveRatio * r.ConsecutiveWeight
+	e.Consecutive = consecutiveRatio * r.ConsecutiveWeight
 
 	// 4. Length ratio: Prefer matches where query is significant portion of text
 	// "tree" matching "tree" is better than "tree" matching "long/path/to/tree/file.txt"
 	lengthRatio := float64(len(query)) / float64(len(text))
-	score += lengthRatio * r.LengthRatioWeight
+	e.LengthRatio = lengthRatio * r.LengthRatioWeight
 
 	// 5. Original position weight: Prefer items that appeared earlier in input
 	// This acts as a tiebreaker for items with similar match quality
 	// Use exponential decay so items far down the list don't dominate
-	// Normalize by assuming max 10000 items, so position 0 gets full bonus
-	maxItems := 10000.0
+	// Normalize by assuming max OriginalPosSpan (default 10000) items, so
+	// position 0 gets full bonus
+	maxItems := float64(defaultPosSpan)
+	if r.OriginalPosSpan > 0 {
+		maxItems = float64(r.OriginalPosSpan)
+	}
 	positionScore := 1.0 - (float64(originalIndex) / maxItems)
 	if positionScore < 0 {
 		positionScore = 0
 	}
-	score += positionScore * r.OriginalPosWeight
+	e.OriginalPos = positionScore * r.OriginalPosWeight
 
-	return score
+	// 6. Path shape: matches in the basename, and at component starts
+	// ("main" in "cmd/main.go" rather than "internal/domain/x.go")
+	if r.BasenameWeight != 0 || r.SeparatorWeight != 0 {
+		inBase, atStart := pathMatches(text, positions)
+		e.Basename = float64(inBase) / float64(len(positions)) * r.BasenameWeight
+		e.Separator = float64(atStart) / float64(len(positions)) * r.SeparatorWeight
+	}
+
+	// 7. Acronym: the query spelled out by word initials
+	if isAcronym(text, positions) {
+		e.Acronym = r.AcronymWeight
+	}
+
+	return e
 }
@@ -1,6 +1,8 @@
 package ranker
 
 import (
+	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/sam33r/goose-launcher/pkg/input"
@@ -56,21 +58,15 @@ func TestRankerScoring(t *testing.T) {
 func TestRankMatches(t *testing.T) {
 	ranker := NewRanker()
 
-	items := []input.Item{
-		{Text: "retrieve.go", Raw: "retrieve.go", Index: 0},
-		{Text: "tree.go", Raw: "tree.go", Index: 1},
-		{Text: "src/tree_utils.go", Raw: "src/tree_utils.go", Index: 2},
-	}
-
 	// Positions for query "tree"
-	positions := map[int][]int{
-		0: {2, 4, 6, 7},   // retrieve - spread out
-		1: {0, 1, 2, 3},   // tree - exact match at start
-		2: {4, 5, 6, 7},   // tree_utils - exact match but later
+	matches := []Match{
+		{Item: input.Item{Text: "retrieve.go", Raw: "retrieve.go", Index: 0}, Positions: []int{2, 4, 6, 7}},             // retrieve - spread out
+		{Item: input.Item{Text: "tree.go", Raw: "tree.go", Index: 1}, Positions: []int{0, 1, 2, 3}},                     // tree - exact match at start
+		{Item: input.Item{Text: "src/tree_utils.go", Raw: "src/tree_utils.go", Index: 2}, Positions: []int{4, 5, 6, 7}}, // tree_utils - exact match but later
 	}
 
 	query := "tree"
-	scores := ranker.RankMatches(items, positions, query)
+	scores := ranker.RankMatches(matches, query)
 
 	if len(scores) != 3 {
 		t.Fatalf("expected 3 scores, got %d", len(scores))
@@ -94,19 +90,14 @@ func TestOriginalPositionWeight(t *testing.T) {
 	ranker := NewRanker()
 
 	// Two items with very similar match quality
-	items := []input.Item{
-		{Text: "tree1.go", Raw: "tree1.go", Index: 0},   // Earlier in list
-		{Text: "tree2.go", Raw: "tree2.go", Index: 100}, // Later in list
-	}
-
 	// Same match positions (exact match at start for both)
-	positions := map[int][]int{
-		0: {0, 1, 2, 3},
-		1: {0, 1, 2, 3},
+	matches := []Match{
+		{Item: input.Item{Text: "tree1.go", Raw: "tree1.go", Index: 0}, Positions: []int{0, 1, 2, 3}},   // Earlier in list
+		{Item: input.Item{Text: "tree2.go", Raw: "tree2.go", Index: 100}, Positions: []int{0, 1, 2, 3}}, // Later in list
 	}
 
 	query := "tree"
-	scores := ranker.RankMatches(items, positions, query)
+	scores := ranker.RankMatches(matches, query)
 
 	if len(scores) != 2 {
 		t.Fatalf("expected 2 scores, got %d", len(scores))
@@ -127,11 +118,10 @@ func TestOriginalPositionWeight(t *testing.T) {
 func TestEmptyMatches(t *testing.T) {
 	ranker := NewRanker()
 
-	items := []input.Item{}
-	positions := map[int][]int{}
+	matches := []Match{}
 	query := "test"
 
-	scores := ranker.RankMatches(items, positions, query)
+	scores := ranker.RankMatches(matches, query)
 
 	if scores != nil {
 		t.Errorf("expected nil for empty items, got %v", scores)
@@ -169,3 +159,178 @@ func TestCompactnessScoring(t *testing.T) {
 			consecutiveScore, spreadScore)
 	}
 }
+
+func TestMatcherScoreBreaksTies(t *testing.T) {
+	ranker := NewRanker()
+
+	// Identical ranker inputs; only the matcher's score differs.
+	matches := []Match{
+		{Item: input.Item{Text: "tree.go", Index: 0}, Positions: []int{0, 1, 2, 3}, Score: 10},
+		{Item: input.Item{Text: "tree.go", Index: 0}, Positions: []int{0, 1, 2, 3}, Score: 90},
+	}
+
+	scores := ranker.RankMatches(matches, "tree")
+
+	if scores[0].MatcherScore != 90 {
+		t.Errorf("expected the higher matcher score first, got %d", scores[0].MatcherScore)
+	}
+}
+
+// Approximate hits (negative matcher score) rank after every exact hit,
+// however well their positions score.
+func TestApproximateMatchesRankLast(t *testing.T) {
+	ranker := NewRanker()
+
+	matches := []Match{
+		{Item: input.Item{Text: "a/b/c/d/tree_test.go", Index: 0}, Positions: []int{8, 9, 10, 11}, Score: 40},
+		{Item: input.Item{Text: "tree", Index: 1}, Positions: []int{0, 1, 2, 3}, Score: -65000},
+	}
+
+	scores := ranker.RankMatches(matches, "tere")
+
+	if scores[0].OriginalIndex != 0 {
+		t.Errorf("expected the exact hit first, got item %d", scores[0].OriginalIndex)
+	}
+}
+
+// Sharded ranking must produce exactly the order of one big sort, ties
+// included.
+func TestRankMatches_ShardedMatchesSingleSort(t *testing.T) {
+	ranker := NewRanker()
+
+	texts := []string{"tree.go", "src/tree.go", "retrieve.go", "a/b/tree_test.go"}
+	positions := [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}, {2, 4, 6, 7}, {4, 5, 6, 7}}
+	matches := make([]Match, 40000)
+	for i := range matches {
+		k := i % len(texts)
+		matches[i] = Match{
+			Item:      input.Item{Text: texts[k], Raw: texts[k], Index: i % 500},
+			Positions: positions[k],
+			Score:     i % 7,
+		}
+	}
+
+	got := ranker.RankMatches(matches, "tree")
+
+	want := make([]MatchScore, len(matches))
+	ranker.rankShard(want, matches, 0, "tree", nil)
+	if len(got) != len(want) {
+		t.Fatalf("got %d scores, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].InputIndex != want[i].InputIndex {
+			t.Fatalf("rank %d: got input #%d, want #%d", i, got[i].InputIndex, want[i].InputIndex)
+		}
+	}
+}
+
+func TestAcronymMatchesRankFirst(t *testing.T) {
+	r := NewRanker()
+	matches := []Match{
+		{Item: input.Item{Text: "cmd/goldfish", Index: 0}, Positions: []int{4, 5, 7}},
+		{Item: input.Item{Text: "goose-launcher-daemon", Index: 1}, Positions: []int{0, 6, 15}},
+	}
+	scores := r.RankMatches(matches, "gld")
+	if scores[0].Item.Text != "goose-launcher-daemon" {
+		t.Errorf("acronym hit should rank first, got %q (%.2f vs %.2f)", scores[0].Item.Text, scores[0].Score, scores[1].Score)
+	}
+
+	tests := []struct {
+		text      string
+		positions []int
+		want      bool
+	}{
+		{"goose-launcher-daemon", []int{0, 6, 15}, true},
+		{"FooBar.go", []int{0, 3}, true},
+		{"FooBar.go", []int{0, 1}, false},     // "o" doesn't start a word
+		{"t r e e", []int{0, 2, 4, 6}, false}, // one-letter words are spelled out
+		{"ünter-über", []int{0, 6}, true},
+		{"foo", []int{0}, false},
+	}
+	for _, tt := range tests {
+		if got := isAcronym(tt.text, tt.positions); got != tt.want {
+			t.Errorf("isAcronym(%q, %v) = %v, want %v", tt.text, tt.positions, got, tt.want)
+		}
+	}
+}
+
+type historyMap map[string]float64
+
+func (h historyMap) Frecency(item input.Item) float64 { return h[item.Raw] }
+
+func TestHistoryBoostsChosenItems(t *testing.T) {
+	matches := []Match{
+		{Item: input.Item{Text: "term.go", Raw: "term.go", Index: 0}, Positions: []int{0, 1, 2}},
+		{Item: input.Item{Text: "src/terminal", Raw: "src/terminal", Index: 1}, Positions: []int{4, 5, 6}},
+	}
+	r := NewRanker()
+	if scores := r.RankMatches(matches, "ter"); scores[0].Item.Raw != "term.go" {
+		t.Fatalf("without history, the earlier tighter match should lead, got %q", scores[0].Item.Raw)
+	}
+
+	r.History = historyMap{"src/terminal": 1}
+	scores := r.RankMatches(matches, "ter")
+	if scores[0].Item.Raw != "src/terminal" {
+		t.Errorf("the favourite should rank first, got %q (%.2f vs %.2f)", scores[0].Item.Raw, scores[0].Score, scores[1].Score)
+	}
+}
+
+type learnedMap map[string]map[string]float64
+
+func (l learnedMap) Picks(query string) map[string]float64 { return l[query] }
+
+func TestLearnedPickRanksFirst(t *testing.T) {
+	r := NewRanker()
+	r.Learned = learnedMap{"ter": {"apps/alacritty-terminal": 1}}
+	matches := []Match{
+		{Item: input.Item{Text: "ter", Raw: "ter", Index: 0}, Positions: []int{0, 1, 2}},
+		{Item: input.Item{Text: "apps/alacritty-terminal", Raw: "apps/alacritty-terminal", Index: 1}, Positions: []int{15, 16, 17}},
+	}
+	scores := r.RankMatches(matches, "ter")
+	if scores[0].Item.Raw != "apps/alacritty-terminal" {
+		t.Errorf("the learned pick should rank first, got %q", scores[0].Item.Raw)
+	}
+	if scores := r.RankMatches(matches, "te"); scores[0].Item.Raw != "ter" {
+		t.Errorf("another query shouldn't get the boost, got %q first", scores[0].Item.Raw)
+	}
+}
+
+// A producer's priority lifts an item over equally good matches, and
+// orders plain filtering too.
+func TestPriorityRanksItemsFirst(t *testing.T) {
+	var matches []Match
+	for i, text := range []string{"term.log", "term.txt", "term.md"} {
+		item := input.Item{Text: text, Raw: text, Index: i}
+		if text == "term.md" {
+			item.Priority = 2
+		}
+		matches = append(matches, Match{Item: item, Positions: []int{0, 1, 2, 3}})
+	}
+	r := NewRanker()
+	if got := r.RankMatches(matches, "term"); got[0].Item.Text != "term.md" {
+		t.Errorf("ranked first: %s, want the prioritized term.md", got[0].Item.Text)
+	}
+
+	order := r.RankPriority(matches, len(matches)).Extend(len(matches))
+	if want := []int32{2, 0, 1}; !reflect.DeepEqual(order, want) {
+		t.Errorf("RankPriority order = %v, want %v (priority, then input order)", order, want)
+	}
+	r.PriorityWeight = 0
+	if order := r.RankPriority(matches, 3).Extend(3); order[0] != 0 {
+		t.Errorf("with PriorityWeight 0, order = %v, want input order", order)
+	}
+}
+
+// Unranked, approximate matches still come after the exact ones, which
+// keep their order whatever their matcher scores.
+func TestRankPriority_ApproximateLast(t *testing.T) {
+	var matches []Match
+	for i, score := range []int{-65536, 3, -131072, 9, 0} {
+		text := "item" + strconv.Itoa(i)
+		matches = append(matches, Match{Item: input.Item{Text: text, Raw: text, Index: i}, Score: score})
+	}
+	order := NewRanker().RankPriority(matches, len(matches)).Extend(len(matches))
+	if want := []int32{1, 3, 4, 0, 2}; !reflect.DeepEqual(order, want) {
+		t.Errorf("RankPriority order = %v, want %v (exact in input order, then approximate)", order, want)
+	}
+}
@@ -0,0 +1,94 @@
+package ranker
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Scheme builds a Ranker tuned for one kind of input. Each Goose plugin
+// picks the scheme that fits its list with --scheme; plugins with inputs
+// of their own shape register a scheme under a new name.
+type Scheme func() *Ranker
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]Scheme{}
+)
+
+// RegisterScheme makes a scheme available by name (e.g. to --scheme).
+// Registering an existing name replaces it, so callers can override the
+// built-ins.
+func RegisterScheme(name string, s Scheme) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[name] = s
+}
+
+// NewScheme builds the ranker of the scheme registered under name.
+func NewScheme(name string) (*Ranker, error) {
+	schemesMu.RLock()
+	s, ok := schemes[name]
+	schemesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown scheme %q (available: %v)", name, SchemeNames())
+	}
+	return s(), nil
+}
+
+// SchemeNames lists the registered schemes in sorted order.
+func SchemeNames() []string {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPathRanker ranks file paths: matches in the basename and at the start
+// of a component count most, and the early-match bonus is measured within
+// the component, so "main" finds "cmd/app/main.go" ahead of
+// "internal/domain/x.go".
+func NewPathRanker() *Ranker {
+	return &Ranker{
+		CompactnessWeight: 30.0,
+		EarlyMatchWeight:  15.0,
+		ConsecutiveWeight: 15.0,
+		LengthRatioWeight: 5.0,
+		OriginalPosWeight: 5.0,
+		BasenameWeight:    20.0,
+		SeparatorWeight:   10.0,
+		Paths:             true,
+		AcronymWeight:     60.0,
+		HistoryWeight:     50.0,
+		LearnedWeight:     1000.0,
+		PriorityWeight:    10.0,
+	}
+}
+
+// NewHistoryRanker ranks lists that are already in order of preference,
+// like shell history (most recent first): input order carries the most
+// weight and fades over the first thousand items, so match quality only
+// lifts an older entry over a recent one by a wide margin.
+func NewHistoryRanker() *Ranker {
+	return &Ranker{
+		CompactnessWeight: 20.0,
+		EarlyMatchWeight:  10.0,
+		ConsecutiveWeight: 10.0,
+		OriginalPosWeight: 60.0,
+		OriginalPosSpan:   1000,
+		AcronymWeight:     60.0,
+		HistoryWeight:     50.0,
+		LearnedWeight:     1000.0,
+		PriorityWeight:    10.0,
+	}
+}
+
+func init() {
+	RegisterScheme("default", NewRanker)
+	RegisterScheme("path", NewPathRanker)
+	RegisterScheme("history", NewHistoryRanker)
+}
@@ -0,0 +1,103 @@
+package ranker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// positionsOf returns the rune indices of sub's first occurrence in text.
+func positionsOf(text, sub string) []int {
+	start := strings.Index(text, sub)
+	positions := make([]int, len(sub))
+	for i := range positions {
+		positions[i] = start + i
+	}
+	return positions
+}
+
+func rankTexts(r *Ranker, query string, texts ...string) []string {
+	matches := make([]Match, len(texts))
+	for i, text := range texts {
+		matches[i] = Match{Item: input.Item{Text: text, Index: i}, Positions: positionsOf(text, query)}
+	}
+	var out []string
+	for _, s := range r.RankMatches(matches, query) {
+		out = append(out, s.Item.Text)
+	}
+	return out
+}
+
+func TestPathScheme_PrefersBasename(t *testing.T) {
+	texts := []string{"main/internal/deep/x.go", "cmd/app/main.go"}
+	if got := rankTexts(NewRanker(), "main", texts...); got[0] != texts[0] {
+		t.Fatalf("default scheme should keep the flat early match first, got %v", got)
+	}
+	if got := rankTexts(NewPathRanker(), "main", texts...); got[0] != "cmd/app/main.go" {
+		t.Errorf("path scheme should rank the basename match first, got %v", got)
+	}
+}
+
+func TestPathScheme_ComponentStart(t *testing.T) {
+	texts := []string{"src/domain/x.go", "src/mainline/x.go"}
+	if got := rankTexts(NewPathRanker(), "main", texts...); got[0] != "src/mainline/x.go" {
+		t.Errorf("a match starting a component should rank first, got %v", got)
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	tests := []struct {
+		text            string
+		positions       []int
+		inBase, atStart int
+	}{
+		{"cmd/app/main.go", []int{8, 9, 10, 11}, 4, 1},
+		{"main/x.go", []int{0, 1, 2, 3}, 0, 1},
+		{"src/pkg/", []int{4, 5, 6}, 3, 1},         // trailing separator
+		{`C:\Users\ünï\a.txt`, []int{9, 13}, 1, 2}, // rune indices, backslashes
+	}
+	for _, tt := range tests {
+		inBase, atStart := pathMatches(tt.text, tt.positions)
+		if inBase != tt.inBase || atStart != tt.atStart {
+			t.Errorf("pathMatches(%q, %v) = %d, %d; want %d, %d", tt.text, tt.positions, inBase, atStart, tt.inBase, tt.atStart)
+		}
+	}
+}
+
+func TestHistoryScheme_FavorsInputOrder(t *testing.T) {
+	// Item 0 is a looser match than item 700, but history puts recency
+	// (input order) first.
+	matches := []Match{
+		{Item: input.Item{Text: "git checkout main", Index: 0}, Positions: []int{0, 4, 9}},
+		{Item: input.Item{Text: "gco", Index: 700}, Positions: []int{0, 1, 2}},
+	}
+	if got := NewRanker().RankMatches(matches, "gco"); got[0].Item.Index != 700 {
+		t.Fatalf("default scheme should prefer the tight match, got index %d first", got[0].Item.Index)
+	}
+	if got := NewHistoryRanker().RankMatches(matches, "gco"); got[0].Item.Index != 0 {
+		t.Errorf("history scheme should prefer the earlier item, got index %d first", got[0].Item.Index)
+	}
+}
+
+func TestSchemeRegistry(t *testing.T) {
+	for _, name := range []string{"default", "path", "history"} {
+		if _, err := NewScheme(name); err != nil {
+			t.Errorf("NewScheme(%q): %v", name, err)
+		}
+	}
+
+	RegisterScheme("test-flat", func() *Ranker { return &Ranker{OriginalPosWeight: 100} })
+	r, err := NewScheme("test-flat")
+	if err != nil {
+		t.Fatalf("custom scheme: %v", err)
+	}
+	if r.OriginalPosWeight != 100 {
+		t.Errorf("custom scheme built the wrong ranker: %+v", r)
+	}
+
+	_, err = NewScheme("nope")
+	if err == nil || !strings.Contains(err.Error(), "history") {
+		t.Errorf("unknown scheme error should list the available ones, got %v", err)
+	}
+}
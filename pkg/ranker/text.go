@@ -0,0 +1,108 @@
+package ranker
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// runeText indexes text by rune without converting ASCII text, the common
+// case. Out-of-range indices read as a space, so callers can look one
+// past either end.
+type runeText struct {
+	text  string
+	runes []rune // nil for ASCII text
+	n     int
+}
+
+func newRuneText(text string) runeText {
+	t := runeText{text: text, n: len(text)}
+	if utf8.RuneCountInString(text) != t.n {
+		t.runes = []rune(text)
+		t.n = len(t.runes)
+	}
+	return t
+}
+
+func (t runeText) at(i int) rune {
+	switch {
+	case i < 0 || i >= t.n:
+		return ' '
+	case t.runes != nil:
+		return t.runes[i]
+	}
+	return rune(t.text[i])
+}
+
+// isAcronym reports whether positions (rune indices into text) are two or
+// more chars that each start a word — at the start of the text, after a
+// non-alphanumeric char, at a camelCase hump or where digits begin — and
+// abbreviate it: a one-char word ("t r e e") is spelled out, not
+// abbreviated.
+func isAcronym(text string, positions []int) bool {
+	if len(positions) < 2 {
+		return false
+	}
+	t := newRuneText(text)
+	for _, p := range positions {
+		if p >= t.n || !wordStart(t.at(p-1), t.at(p)) {
+			return false
+		}
+		if next := t.at(p + 1); !alnum(next) || wordStart(t.at(p), next) {
+			return false
+		}
+	}
+	return true
+}
+
+func wordStart(prev, cur rune) bool {
+	switch {
+	case !alnum(cur):
+		return false
+	case !alnum(prev):
+		return true
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return true
+	}
+	return !unicode.IsDigit(prev) && unicode.IsDigit(cur)
+}
+
+func alnum(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+func isPathSeparator(r rune) bool { return r == '/' || r == '\\' }
+
+// componentOffset returns how far rune index p is into its path component.
+func componentOffset(text string, p int) int {
+	t := newRuneText(text)
+	start := min(p, t.n)
+	for start > 0 && !isPathSeparator(t.at(start-1)) {
+		start--
+	}
+	return p - start
+}
+
+// pathMatches counts the positions in text's basename — its last
+// component; trailing separators belong to it, so "src/pkg/" has basename
+// "pkg/" — and those that start a component.
+func pathMatches(text string, positions []int) (inBase, atStart int) {
+	t := newRuneText(text)
+	end := t.n
+	for end > 0 && isPathSeparator(t.at(end-1)) {
+		end--
+	}
+	base := end
+	for base > 0 && !isPathSeparator(t.at(base-1)) {
+		base--
+	}
+	for _, p := range positions {
+		if p >= t.n {
+			continue
+		}
+		if p >= base {
+			inBase++
+		}
+		if p == 0 || isPathSeparator(t.at(p-1)) {
+			atStart++
+		}
+	}
+	return inBase, atStart
+}
@@ -0,0 +1,175 @@
+package ranker
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+// Criterion is one link of a tiebreak chain (--tiebreak): a way of
+// ordering two matches that the links before it found equal.
+type Criterion int
+
+const (
+	// ByScore prefers the higher ranker score, then the higher matcher
+	// score.
+	ByScore Criterion = iota
+	// ByLength prefers the shorter item text.
+	ByLength
+	// ByBegin prefers the match starting earlier in the text.
+	ByBegin
+	// ByEnd prefers the match ending closer to the end of the text.
+	ByEnd
+	// ByIndex prefers the item earlier in the input.
+	ByIndex
+)
+
+var criterionNames = map[string]Criterion{
+	"score":  ByScore,
+	"length": ByLength,
+	"begin":  ByBegin,
+	"end":    ByEnd,
+	"index":  ByIndex,
+}
+
+// ParseTiebreak parses a comma-separated tiebreak chain such as
+// "length,begin". As in fzf, the score comes first unless the chain places
+// it, and input order settles whatever the chain leaves tied.
+func ParseTiebreak(spec string) ([]Criterion, error) {
+	var chain []Criterion
+	seen := map[Criterion]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		c, ok := criterionNames[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown tiebreak %q (want score, length, begin, end or index)", name)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("duplicate tiebreak %q", name)
+		}
+		seen[c] = true
+		chain = append(chain, c)
+	}
+	if !seen[ByScore] {
+		chain = append([]Criterion{ByScore}, chain...)
+	}
+	return chain, nil
+}
+
+// sortKey is what ordering a match needs: far smaller than a MatchScore,
+// so lazy rankings (see RankLazy) can hold one per match. The span fields
+// are filled only when the chain compares them.
+type sortKey struct {
+	score        float64
+	matcherScore int
+	index        int32 // InputIndex
+	length       int32 // Text length in runes
+	begin, end   int32 // Highlighted span, end exclusive
+}
+
+// keyOf builds m's sort key under r's chain.
+func (r *Ranker) keyOf(m *MatchScore) sortKey {
+	k := sortKey{score: m.Score, matcherScore: m.MatcherScore, index: int32(m.InputIndex)}
+	if r.comparesSpans() {
+		k.length, k.begin, k.end = spanOf(m.Item, m.Positions)
+	}
+	return k
+}
+
+// comparesSpans reports whether r's chain looks past score and input order.
+func (r *Ranker) comparesSpans() bool {
+	for _, c := range r.Tiebreak {
+		if c == ByLength || c == ByBegin || c == ByEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// spanOf returns the text length in runes (the unit of positions) and the
+// highlighted span. Matches without positions span nothing and tie with
+// each other.
+func spanOf(item input.Item, positions []int) (length, begin, end int32) {
+	n := len(item.Text)
+	if !item.ASCII {
+		n = utf8.RuneCountInString(item.Text)
+	}
+	length, end = int32(n), int32(n)
+	if len(positions) > 0 {
+		begin, end = int32(positions[0]), int32(positions[len(positions)-1]+1)
+	}
+	return length, begin, end
+}
+
+// Before reports whether a sorts before b in r's output: exact hits
+// before approximate ones (a negative matcher score, e.g. --typos), then
+// r's Tiebreak chain, then input order. Scores don't depend on the other
+// matches, so callers can merge separately ranked batches with it.
+func (r *Ranker) Before(a, b *MatchScore) bool {
+	if r.Tiebreak == nil {
+		return RankedBefore(a, b)
+	}
+	ka, kb := r.keyOf(a), r.keyOf(b)
+	return r.keyBefore(&ka, &kb)
+}
+
+// keyBefore is Before on sort keys.
+func (r *Ranker) keyBefore(a, b *sortKey) bool {
+	return r.keyCompare(a, b) < 0
+}
+
+// keyCompare is negative when a sorts first, positive when b does; 0 only
+// for a key compared with itself.
+func (r *Ranker) keyCompare(a, b *sortKey) int {
+	if approxA, approxB := a.matcherScore < 0, b.matcherScore < 0; approxA != approxB {
+		if approxB {
+			return -1
+		}
+		return 1
+	}
+	if r.Tiebreak == nil {
+		// The default chain, spelled out for the hot path.
+		if d := compareBy(ByScore, a, b); d != 0 {
+			return d
+		}
+	}
+	for _, c := range r.Tiebreak {
+		if d := compareBy(c, a, b); d != 0 {
+			return d
+		}
+	}
+	return cmp.Compare(a.index, b.index)
+}
+
+// scoreThenIndex is the default chain, spelled out for the hot path.
+func scoreThenIndex(a, b *MatchScore) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.MatcherScore != b.MatcherScore {
+		return a.MatcherScore > b.MatcherScore
+	}
+	return a.InputIndex < b.InputIndex
+}
+
+// compareBy is negative when c puts a first, positive when b, 0 on a tie.
+func compareBy(c Criterion, a, b *sortKey) int {
+	switch c {
+	case ByScore:
+		if d := cmp.Compare(b.score, a.score); d != 0 {
+			return d
+		}
+		return cmp.Compare(b.matcherScore, a.matcherScore)
+	case ByLength:
+		return cmp.Compare(a.length, b.length)
+	case ByBegin:
+		return cmp.Compare(a.begin, b.begin)
+	case ByEnd:
+		return cmp.Compare(a.length-a.end, b.length-b.end)
+	case ByIndex:
+		return cmp.Compare(a.index, b.index)
+	}
+	return 0
+}
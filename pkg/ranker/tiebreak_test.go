@@ -0,0 +1,106 @@
+package ranker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+)
+
+func TestParseTiebreak(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []Criterion
+	}{
+		{"score,length,begin,end,index", []Criterion{ByScore, ByLength, ByBegin, ByEnd, ByIndex}},
+		{"length", []Criterion{ByScore, ByLength}}, // score leads unless placed
+		{"begin,score", []Criterion{ByBegin, ByScore}},
+	}
+	for _, tt := range tests {
+		got, err := ParseTiebreak(tt.spec)
+		if err != nil {
+			t.Errorf("ParseTiebreak(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTiebreak(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "chunk", "length,length"} {
+		if _, err := ParseTiebreak(bad); err == nil {
+			t.Errorf("ParseTiebreak(%q) should fail", bad)
+		}
+	}
+}
+
+// Equal scores fall to the chain: length, then where the match begins and
+// ends, then input order.
+func TestTiebreakChain(t *testing.T) {
+	matches := []Match{
+		{Item: input.Item{Text: "xx-foo-bar", ASCII: true}, Positions: []int{3, 4, 5}},
+		{Item: input.Item{Text: "foo-bar", ASCII: true}, Positions: []int{0, 1, 2}},
+		{Item: input.Item{Text: "bar-foo", ASCII: true}, Positions: []int{4, 5, 6}},
+	}
+	// Only the tiebreak differs between items.
+	r := &Ranker{}
+	order := func(spec string) string {
+		chain, err := ParseTiebreak(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Tiebreak = chain
+		var texts []string
+		for _, s := range r.RankMatches(matches, "foo") {
+			texts = append(texts, s.Item.Text)
+		}
+		return strings.Join(texts, " ")
+	}
+	tests := []struct{ spec, want string }{
+		{"index", "xx-foo-bar foo-bar bar-foo"},
+		{"length", "foo-bar bar-foo xx-foo-bar"},
+		{"begin", "foo-bar xx-foo-bar bar-foo"},
+		{"end", "bar-foo xx-foo-bar foo-bar"},
+	}
+	for _, tt := range tests {
+		if got := order(tt.spec); got != tt.want {
+			t.Errorf("--tiebreak=%s: %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestRankerSettings(t *testing.T) {
+	r := NewRanker()
+	if err := r.SetWeights("compactness=50, early=0,position-span=500"); err != nil {
+		t.Fatalf("SetWeights: %v", err)
+	}
+	if r.CompactnessWeight != 50 || r.EarlyMatchWeight != 0 || r.OriginalPosSpan != 500 {
+		t.Errorf("weights not applied: %+v", r)
+	}
+	for _, bad := range []string{"compactness", "speed=3", "early=fast", "position-span=-1"} {
+		if err := r.SetWeights(bad); err == nil {
+			t.Errorf("SetWeights(%q) should fail", bad)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "files.conf")
+	conf := "# files plugin\n\nbasename = 30\ntiebreak = length,begin\n"
+	if err := os.WriteFile(path, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if r.BasenameWeight != 30 || !reflect.DeepEqual(r.Tiebreak, []Criterion{ByScore, ByLength, ByBegin}) {
+		t.Errorf("config not applied: basename %v, tiebreak %v", r.BasenameWeight, r.Tiebreak)
+	}
+
+	if err := os.WriteFile(path, []byte("basename 30\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadConfig(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("LoadConfig of a bad line = %v, want an error naming line 1", err)
+	}
+}
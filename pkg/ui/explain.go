@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"github.com/sam33r/goose-launcher/pkg/input"
+	"github.com/sam33r/goose-launcher/pkg/ranker"
+)
+
+// SetExplain turns --explain on or off. With it on, every accepted line
+// is printed with its score breakdown as a second, tab-separated column,
+// and the cursor row shows its own in an overlay (Ctrl+E toggles it).
+// Like SetRanker, call it after Configure/ConfigureEmpty.
+func (w *Window) SetExplain(on bool) {
+	w.explain = on
+	w.explainShown = on
+}
+
+// explainItem breaks down the score item ranks by under the current
+// query (what it would rank by, without --rank). ok is false when the
+// item doesn't match the query, e.g. a row marked under an earlier one.
+func (w *Window) explainItem(item input.Item) (e ranker.Explanation, ok bool) {
+	query := w.searchInput.Text()
+	ok, positions, score := w.matcher.Match(query, item)
+	if !ok {
+		return e, false
+	}
+	return w.ranker.Explain(query, ranker.Match{Item: item, Positions: positions, Score: score}), true
+}
+
+// outputLine is an accepted item as printed: its raw line, plus the score
+// breakdown column with --explain ("-" for items the query doesn't match).
+func (w *Window) outputLine(item input.Item) string {
+	if !w.explain {
+		return item.Raw
+	}
+	e, ok := w.explainItem(item)
+	if !ok {
+		return item.Raw + "\t-"
+	}
+	return item.Raw + "\t" + e.String()
+}
+
+// explainOverlay is the text the list overlays on the cursor row: its
+// score breakdown while the --explain overlay is shown, else "".
+func (w *Window) explainOverlay() string {
+	row := w.list.Selected()
+	if !w.explainShown || row >= len(w.filtered) {
+		return ""
+	}
+	e, ok := w.explainItem(w.filtered[row])
+	if !ok {
+		return "no match"
+	}
+	return e.String()
+}
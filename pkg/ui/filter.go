@@ -0,0 +1,607 @@
+package ui
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/sam33r/goose-launcher/pkg/input"
+	"github.com/sam33r/goose-launcher/pkg/matcher"
+	"github.com/sam33r/goose-launcher/pkg/ranker"
+)
+
+// asyncFilterThreshold is the item count from which layout hands filtering
+// to the worker goroutine. Smaller inputs filter in a couple of
+// milliseconds, well inside a frame, and doing them inline avoids a
+// one-frame flash of stale results.
+const asyncFilterThreshold = 10000
+
+// filterDebounce is how long the worker waits for a follow-up keystroke
+// before filtering n items. Below 100k a pass is quicker than the gap
+// between keystrokes, so there's no wait; above it, a fast typist would
+// otherwise queue a full pass per character.
+func filterDebounce(n int) time.Duration {
+	switch {
+	case n < 100000:
+		return 0
+	case n < 1000000:
+		return 30 * time.Millisecond
+	default:
+		return 60 * time.Millisecond
+	}
+}
+
+// filterJob is one filter pass with everything it reads snapshotted, so it
+// can run off the event loop. items is safe to share: the event loop only
+// ever appends past the snapshot's length.
+type filterJob struct {
+	ctx           context.Context
+	seq           uint64
+	query         string
+	items         []input.Item
+	base          *filterEntry          // cached pass to build on (see filterCache.base); nil for a full scan
+	index         *matcher.TrigramIndex // narrows a full scan when the matcher can use it; nil if none
+	matcher       matcher.Matcher
+	ranker        *ranker.Ranker
+	rank          bool
+	prioritize    bool // order by item priority when not ranking (see RankPriority)
+	approximate   bool // the matcher returns approximate hits, which go below exact ones
+	needPositions bool
+	want          matcher.Want // what matching computes per hit: scores only when ranking
+	debounce      time.Duration
+}
+
+// filterResult is a finished job, published back to the event loop.
+// filtered and positions are the entry rendered by the worker; both are nil
+// when the entry only appends to its base, which the event loop does in
+// place.
+type filterResult struct {
+	seq       uint64
+	entry     *filterEntry
+	filtered  []input.Item
+	positions map[int][]int
+}
+
+// newFilterJob snapshots the current request state for query.
+func (w *Window) newFilterJob(query string) *filterJob {
+	return &filterJob{
+		ctx:   w.filterCtx,
+		seq:   w.filterSeq,
+		query: query,
+		items: w.items,
+		base:  w.filterCache.base(w.matcher, query),
+		index: w.currentIndex(),
+		// Whether downstream consumers actually need positions; skipping
+		// the allocation cuts ~1 alloc/match for the
+		// --highlight-matches=false path.
+		needPositions: w.highlightMatches || w.rankEnabled,
+		want:          w.filterWant(),
+		matcher:       w.matcher,
+		ranker:        w.ranker,
+		rank:          w.rankEnabled,
+		prioritize:    w.prioritized,
+		approximate:   w.approximate(),
+	}
+}
+
+// filterWant is what a filter pass must compute per hit: the matcher
+// score only feeds the ranker and tells approximate hits apart, and
+// positions only highlighting and ranking.
+func (w *Window) filterWant() matcher.Want {
+	switch {
+	case w.rankEnabled || w.approximate():
+		return matcher.WantScore
+	case w.highlightMatches:
+		return matcher.WantPositions
+	}
+	return matcher.WantMatch
+}
+
+// run filters j.items into a new entry. rankInput is a reusable buffer for
+// the ranking pass. ok is false if the job was cancelled.
+func (j *filterJob) run(rankInput []ranker.Match) (entry *filterEntry, _ []ranker.Match, ok bool) {
+	ctx := j.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	hits, err := j.match(ctx)
+	if err != nil {
+		return nil, rankInput, false
+	}
+	entry = &filterEntry{query: j.query, n: len(j.items), hits: hits}
+
+	// Optional ranking pass. Every hit is scored, but only the first
+	// rankedRows are put in order; the window ranks further as the user
+	// scrolls (ensureRanked). When the items grew under the same query,
+	// only the new hits are scored, and their ranked prefix merged into
+	// the base's (see Ranking.Merge).
+	// Unranked results are still ordered by priority when items have one,
+	// and approximate hits moved below the exact ones.
+	if (j.rank || j.prioritize || j.approximate) && len(hits) > 0 {
+		from := 0
+		if j.base != nil && j.base.query == j.query {
+			from = len(j.base.hits)
+		}
+		rankInput = rankInput[:0]
+		for _, h := range hits[from:] {
+			rankInput = append(rankInput, ranker.Match{Item: j.items[h.Index], Positions: h.Positions, Score: h.Score})
+		}
+		var ranking *ranker.Ranking
+		if j.rank {
+			ranking = j.ranker.RankLazy(rankInput, j.query, rankedRows)
+		} else {
+			ranking = j.ranker.RankPriority(rankInput, rankedRows)
+		}
+		if ctx.Err() != nil {
+			return nil, rankInput, false
+		}
+		if from == 0 {
+			entry.ranking = ranking
+		} else {
+			entry.ranking = j.base.ranking.Merge(ranking, rankedRows)
+			entry.rankBase = j.base.ranking
+		}
+	}
+	return entry, rankInput, true
+}
+
+// match finds the hits for j.query in input order. Matching fans out across
+// GOMAXPROCS workers on large inputs, with results identical to a serial
+// walk. With a base, only the base's hits are searched (none at all when
+// it's for the same query) plus the items that arrived after it, so typing
+// on and streaming both cost only what changed. Without one, a trigram
+// index narrows substring queries to the items that can match.
+func (j *filterJob) match(ctx context.Context) ([]matcher.Result, error) {
+	if j.query == "" {
+		// Only filtered to be put in priority order: every item is a hit.
+		hits := make([]matcher.Result, len(j.items))
+		for i := range hits {
+			hits[i].Index = i
+		}
+		return hits, nil
+	}
+	if j.base == nil {
+		if cands, ok := matcher.Candidates(j.matcher, j.index, j.query); ok {
+			return matcher.RefineContext(ctx, j.matcher, j.query, j.items, cands, j.want)
+		}
+		return matcher.FilterContext(ctx, j.matcher, j.query, j.items, j.want)
+	}
+	hits := slices.Clip(j.base.hits) // appending must not write into the cached entry
+	if j.base.query != j.query {
+		var err error
+		hits, err = matcher.RefineContext(ctx, j.matcher, j.query, j.items, j.base.hits, j.want)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if j.base.n == len(j.items) {
+		return hits, nil
+	}
+	tail, err := matcher.FilterContext(ctx, j.matcher, j.query, j.items[j.base.n:], j.want)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tail {
+		tail[i].Index += j.base.n
+	}
+	return append(hits, tail...), nil
+}
+
+// requestFilter is layout's entry point: it brings the results up to date
+// with query and the current items. Small inputs (and the empty query) are
+// filtered inline; large ones become a job for the worker, and the results
+// land via drainFilterResults on a later frame. Windows built without the
+// worker (tests) always filter inline.
+func (w *Window) requestFilter(query string) {
+	if w.filterJobs == nil || (query == "" && !w.prioritized) || len(w.items) < asyncFilterThreshold {
+		if w.filterStale(query) {
+			// Anything still in flight is for an older query or item set.
+			w.supersedeFilterJobs()
+		}
+		w.filterItems(query)
+		return
+	}
+	if !w.filterStale(query) {
+		return
+	}
+	// Growth of the item set under an unchanged query doesn't invalidate
+	// the job in flight: its results are still right for the items it saw,
+	// and cancelling on every streamed batch would starve the list.
+	growthOnly := w.hasFiltered && query == w.lastQuery
+	w.markFiltered(query)
+
+	if !w.checkQuery(query) {
+		return
+	}
+	if !growthOnly {
+		w.supersedeFilterJobs()
+	}
+	// Backspacing to a query we've seen: restore it without a pass. (Never
+	// hits on growth, which is a new generation.)
+	if w.showCachedFilter(query) {
+		return
+	}
+
+	w.filterSeq++
+	job := w.newFilterJob(query)
+	job.debounce = filterDebounce(len(job.items))
+	// One-slot mailbox: a job the worker hasn't picked up yet is stale.
+	for {
+		select {
+		case w.filterJobs <- job:
+			return
+		default:
+			select {
+			case <-w.filterJobs:
+			default:
+			}
+		}
+	}
+}
+
+// supersedeFilterJobs cancels the jobs in flight and marks their results
+// stale, e.g. because the query, the matcher or the request changed.
+func (w *Window) supersedeFilterJobs() {
+	if w.filterCancel != nil {
+		w.filterCancel()
+	}
+	if w.filterJobs != nil {
+		w.filterCtx, w.filterCancel = context.WithCancel(context.Background())
+	}
+	w.filterBaseSeq = w.filterSeq + 1
+	w.appliedSeq = w.filterSeq
+}
+
+// filtering reports whether a submitted job hasn't published yet; the
+// count line shows "filtering…" meanwhile.
+func (w *Window) filtering() bool {
+	return w.appliedSeq < w.filterSeq
+}
+
+// settleFilter brings the results up to date with the query before an
+// accept. A job still in flight (or a query typed since the last frame)
+// means the list shows another query's rows, and Enter must not output
+// one of those: the pending pass runs here, inline, instead.
+func (w *Window) settleFilter() {
+	w.drainFilterResults()
+	query := w.searchInput.Text()
+	if !w.filtering() && !w.filterStale(query) {
+		return
+	}
+	w.supersedeFilterJobs()
+	w.hasFiltered = false // the superseded job's query was marked filtered
+	w.filterItems(query)
+	if w.list.selected >= len(w.filtered) {
+		w.list.selected = max(len(w.filtered)-1, 0)
+	}
+}
+
+// runFilterWorker executes filter jobs for the lifetime of the window.
+// Started by newWindowShell.
+func (w *Window) runFilterWorker() {
+	for job := range w.filterJobs {
+		// Debounce: give a follow-up keystroke the chance to replace this
+		// job before paying for a full pass.
+		for job.debounce > 0 {
+			timer := time.NewTimer(job.debounce)
+			select {
+			case newer := <-w.filterJobs:
+				timer.Stop()
+				job = newer
+				continue
+			case <-timer.C:
+			}
+			break
+		}
+		if job.ctx.Err() != nil {
+			continue
+		}
+
+		entry, _, ok := job.run(nil)
+		if !ok {
+			continue
+		}
+		res := &filterResult{seq: job.seq, entry: entry}
+		// Render here, off the event loop, unless the event loop can just
+		// add the new hits to what it already shows.
+		if !entry.grows(job.base) {
+			res.positions = make(map[int][]int)
+			res.filtered = entry.materialize(job.items, nil, 0, res.positions, job.needPositions)
+		}
+		w.publishFilterResult(res)
+	}
+}
+
+// publishFilterResult hands a result to the event loop the same way
+// AppendItems hands over items: through a channel drained at the top of
+// layout, plus Invalidate to wake it. An unread older result is replaced.
+func (w *Window) publishFilterResult(res *filterResult) {
+	for {
+		select {
+		case w.filterResults <- res:
+			if w.app != nil {
+				w.app.Invalidate()
+			}
+			return
+		default:
+			select {
+			case <-w.filterResults:
+			default:
+			}
+		}
+	}
+}
+
+// drainFilterResults applies the newest published result that is still
+// current. Must run on the event-loop goroutine; called at the top of
+// layout().
+func (w *Window) drainFilterResults() {
+	if w.filterResults == nil {
+		return
+	}
+	for {
+		select {
+		case res := <-w.filterResults:
+			if res.seq < w.filterBaseSeq || res.seq <= w.appliedSeq {
+				continue
+			}
+			w.appliedSeq = res.seq
+			if res.positions != nil {
+				w.filtered = res.filtered
+				w.filteredOwned = res.filtered
+				w.matchPositions = res.positions
+				w.shownFilter = res.entry
+			} else {
+				w.showFilterEntry(res.entry)
+			}
+			w.filterCache.add(res.entry)
+		default:
+			return
+		}
+	}
+}
+
+// showCachedFilter puts the cached result for query and the current items
+// on screen. Reports false on a cache miss.
+func (w *Window) showCachedFilter(query string) bool {
+	e := w.filterCache.get(query, len(w.items))
+	if e == nil {
+		return false
+	}
+	w.showFilterEntry(e)
+	return true
+}
+
+// showFilterEntry renders e into filtered and matchPositions, reusing their
+// buffers. When e only adds hits to the entry on screen — the items grew
+// under an unchanged query — the new hits are appended in place, or for a
+// ranked entry, merged in (see showMergedRanking).
+func (w *Window) showFilterEntry(e *filterEntry) {
+	needPositions := w.highlightMatches || w.rankEnabled
+	if w.showMergedRanking(e, needPositions) {
+		w.filtered = w.filteredOwned
+		w.shownFilter = e
+		return
+	}
+	from := 0
+	if e.appendsTo(w.shownFilter) {
+		from = len(w.shownFilter.hits)
+	} else {
+		for k := range w.matchPositions {
+			delete(w.matchPositions, k)
+		}
+		w.filteredOwned = w.filteredOwned[:0]
+	}
+	w.filteredOwned = e.materialize(w.items, w.filteredOwned, from, w.matchPositions, needPositions)
+	w.filtered = w.filteredOwned
+	w.shownFilter = e
+}
+
+// showMergedRanking updates the rows on screen for e when e's ranking
+// merged new hits into the one shown, rewriting only the rows that
+// changed: the ranked prefix, and rows appended past the old end. The
+// merged prefix begins with the top of the shown ranking, in order; the
+// shown rows it no longer holds, then the new hits outside it, are
+// appended, unranked like the rest. Reports false, having changed
+// nothing, when the rows on screen aren't the ones e was merged onto.
+func (w *Window) showMergedRanking(e *filterEntry, needPositions bool) bool {
+	prev := w.shownFilter
+	if e.rankBase == nil || prev == nil || prev.ranking != e.rankBase || len(w.filteredOwned) != len(prev.hits) {
+		return false
+	}
+	shownOrder := prev.ranking.Extend(0)
+	ranked := e.ranking.Extend(0)
+	shown, n, k := len(shownOrder), len(prev.hits), len(ranked)
+	// Past the prefix, the rows on screen stay put only if the prefix
+	// still ends where it did (or nothing followed it).
+	if k < shown || (k > shown && shown < n) {
+		return false
+	}
+	kept := 0                               // shown ranked rows the merged prefix keeps
+	inPrefix := make([]bool, len(e.hits)-n) // new hits in the merged prefix
+	for _, idx := range ranked {
+		if int(idx) < n {
+			kept++
+		} else {
+			inPrefix[int(idx)-n] = true
+		}
+	}
+	row := 0
+	put := func(idx int32) {
+		h := &e.hits[idx]
+		if row < len(w.filteredOwned) {
+			w.filteredOwned[row] = w.items[h.Index]
+		} else {
+			w.filteredOwned = append(w.filteredOwned, w.items[h.Index])
+		}
+		if needPositions {
+			w.matchPositions[row] = h.Positions
+		}
+		row++
+	}
+	for _, idx := range ranked {
+		put(idx)
+	}
+	row = max(row, n)
+	for _, idx := range shownOrder[kept:] {
+		put(idx)
+	}
+	for i, in := range inPrefix {
+		if !in {
+			put(int32(n + i))
+		}
+	}
+	return true
+}
+
+// rankedRows is how many rows a ranking pass puts in order up front:
+// several screens, more than most users ever scroll through.
+const rankedRows = 256
+
+// ensureRanked makes sure the first n rows on screen are in rank order,
+// sorting the shown ranking further when the user scrolls or pages past
+// what's ranked. It ranks ahead by doubling, so scrolling to the end
+// costs O(n log n) overall, like a full sort. The rows from the old
+// ranked prefix on are rewritten; reports whether there were any.
+func (w *Window) ensureRanked(n int) bool {
+	e := w.shownFilter
+	if e == nil || e.ranking == nil {
+		return false
+	}
+	sorted := e.ranking.Sorted()
+	if n <= sorted || sorted == e.ranking.Len() {
+		return false
+	}
+	e.ranking.Extend(max(n+rankedRows, 2*sorted))
+	needPositions := w.highlightMatches || w.rankEnabled
+	w.filteredOwned = e.materialize(w.items, w.filteredOwned[:sorted], sorted, w.matchPositions, needPositions)
+	w.filtered = w.filteredOwned
+	return true
+}
+
+const (
+	// filterCacheEntries bounds how many recent queries the result cache
+	// remembers — enough to backspace through a typical query.
+	filterCacheEntries = 16
+	// filterCacheMaxHits bounds the total hits held across entries, so a
+	// few broad queries over a million items don't pin hundreds of MB.
+	filterCacheMaxHits = 2000000
+)
+
+// filterEntry is the outcome of one filter pass over the first n items:
+// the hits in input order (also the starting point for narrowing a longer
+// query) and, when ranked, the display order. Entries are never modified
+// once built, except that the ranking sorts further on demand (it's safe
+// for concurrent use); the worker reads them as job bases.
+type filterEntry struct {
+	query    string
+	n        int
+	hits     []matcher.Result
+	ranking  *ranker.Ranking // display order over hits; nil when unranked
+	rankBase *ranker.Ranking // the ranking Merge built ranking from; nil for a fresh one
+}
+
+// appendsTo reports whether e is prev plus hits from later items: the same
+// unranked query over more items. Both must come from the same request and
+// matcher, which holds for the entries in filterCache and on screen.
+func (e *filterEntry) appendsTo(prev *filterEntry) bool {
+	return prev != nil && prev.query == e.query && prev.n <= e.n && prev.ranking == nil && e.ranking == nil
+}
+
+// grows reports whether e adds hits to prev, so the rows prev shows can
+// be updated in place rather than rebuilt: an unranked entry appends to
+// it, a ranked one merged its new hits into prev's ranking.
+func (e *filterEntry) grows(prev *filterEntry) bool {
+	return e.appendsTo(prev) || (prev != nil && e.rankBase != nil && e.rankBase == prev.ranking)
+}
+
+// materialize appends the entry's items from display index from onwards
+// to dst and records their positions, keyed by display index. Past the
+// ranked prefix, ranked entries list the remaining hits in no particular
+// order until ensureRanked sorts them.
+func (e *filterEntry) materialize(items, dst []input.Item, from int, positions map[int][]int, needPositions bool) []input.Item {
+	var order []int32
+	if e.ranking != nil {
+		order = e.ranking.Order(make([]int32, 0, len(e.hits)))
+	}
+	for i := from; i < len(e.hits); i++ {
+		h := &e.hits[i]
+		if order != nil {
+			h = &e.hits[order[i]]
+		}
+		dst = append(dst, items[h.Index])
+		if needPositions {
+			positions[i] = h.Positions
+		}
+	}
+	return dst
+}
+
+// filterCache is a small LRU of recent filter passes, most recently used
+// last, one entry per query. Every entry covers a prefix of the current
+// items: the window clears the cache whenever the items or the matcher are
+// replaced, and otherwise items only grow. Owned by the event loop.
+type filterCache struct {
+	entries []*filterEntry
+}
+
+// get returns the entry for query over exactly the first n items.
+func (c *filterCache) get(query string, n int) *filterEntry {
+	for i, e := range c.entries {
+		if e.query == query && e.n == n {
+			c.touch(i)
+			return e
+		}
+	}
+	return nil
+}
+
+// base returns the cached pass a job for query can build on: the same
+// query over fewer items if there is one, otherwise the one with the
+// longest query that m says query narrows. nil means a full scan.
+func (c *filterCache) base(m matcher.Matcher, query string) *filterEntry {
+	var best *filterEntry
+	for _, e := range c.entries {
+		if e.query == query {
+			return e
+		}
+		if best != nil && len(e.query) <= len(best.query) {
+			continue
+		}
+		if matcher.Narrows(m, e.query, query) {
+			best = e
+		}
+	}
+	return best
+}
+
+// add records a finished pass, replacing any older pass for its query.
+func (c *filterCache) add(e *filterEntry) {
+	if e == nil {
+		return
+	}
+	kept := c.entries[:0]
+	total := len(e.hits)
+	for _, old := range c.entries {
+		if old.query != e.query {
+			kept = append(kept, old)
+			total += len(old.hits)
+		}
+	}
+	for len(kept) > 0 && (len(kept) >= filterCacheEntries || total > filterCacheMaxHits) {
+		total -= len(kept[0].hits)
+		kept = kept[1:]
+	}
+	c.entries = append(kept, e)
+}
+
+func (c *filterCache) touch(i int) {
+	e := c.entries[i]
+	copy(c.entries[i:], c.entries[i+1:])
+	c.entries[len(c.entries)-1] = e
+}
+
+func (c *filterCache) clear() {
+	c.entries = nil
+}
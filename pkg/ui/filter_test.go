@@ -0,0 +1,514 @@
+package ui
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appinput "github.com/sam33r/goose-launcher/pkg/input"
+	"github.com/sam33r/goose-launcher/pkg/matcher"
+	"github.com/sam33r/goose-launcher/pkg/ranker"
+)
+
+// newAsyncTestWindow is newBenchWindow with the filter worker running, as
+// newWindowShell sets it up (minus the Gio app.Window).
+func newAsyncTestWindow(t *testing.T, items []appinput.Item) *Window {
+	w := newBenchWindow(items)
+	w.filterJobs = make(chan *filterJob, 1)
+	w.filterResults = make(chan *filterResult, 1)
+	w.filterCtx, w.filterCancel = context.WithCancel(context.Background())
+	go w.runFilterWorker()
+	t.Cleanup(func() { close(w.filterJobs) })
+	return w
+}
+
+// waitForFilter drains worker results, the way successive frames would,
+// until the window has caught up with its latest job.
+func waitForFilter(t *testing.T, w *Window) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for w.filtering() {
+		if time.Now().After(deadline) {
+			t.Fatal("filter worker never published the latest job")
+		}
+		time.Sleep(time.Millisecond)
+		w.drainFilterResults()
+	}
+}
+
+func TestRequestFilter_AsyncMatchesSync(t *testing.T) {
+	for _, rank := range []bool{false, true} {
+		items := generateBenchItems(50000)
+
+		sync := newBenchWindow(items)
+		sync.rankEnabled = rank
+		sync.ranker = ranker.NewRanker()
+		sync.filterItems("handler")
+
+		async := newAsyncTestWindow(t, items)
+		async.rankEnabled = rank
+		async.ranker = ranker.NewRanker()
+		async.requestFilter("handler")
+		if !async.filtering() {
+			t.Fatal("expected a job in flight for a large input")
+		}
+		waitForFilter(t, async)
+
+		if !reflect.DeepEqual(async.filtered, sync.filtered) {
+			t.Errorf("rank=%v: async results differ from sync (%d vs %d items)", rank, len(async.filtered), len(sync.filtered))
+		}
+		if !reflect.DeepEqual(async.matchPositions, sync.matchPositions) {
+			t.Errorf("rank=%v: async positions differ from sync", rank)
+		}
+	}
+}
+
+// A newer keystroke supersedes the job in flight; only its results land.
+func TestRequestFilter_NewerQueryWins(t *testing.T) {
+	w := newAsyncTestWindow(t, generateBenchItems(50000))
+
+	for _, q := range []string{"h", "ha", "han", "hand", "handler/4"} {
+		w.requestFilter(q)
+	}
+	waitForFilter(t, w)
+
+	want := newBenchWindow(w.items)
+	want.filterItems("handler/4")
+	if !reflect.DeepEqual(w.filtered, want.filtered) {
+		t.Errorf("got %d items, want the %d for the last query", len(w.filtered), len(want.filtered))
+	}
+}
+
+func TestDrainFilterResults_DropsStale(t *testing.T) {
+	w := newBenchWindow(generateBenchItems(10))
+	w.filterResults = make(chan *filterResult, 1)
+	current := w.filtered
+
+	// A job is submitted, then superseded (new query or new request).
+	w.filterSeq = 1
+	w.supersedeFilterJobs()
+	w.filterSeq = 2
+
+	w.filterResults <- &filterResult{seq: 1, filtered: nil, positions: map[int][]int{}}
+	w.drainFilterResults()
+	if len(w.filtered) != len(current) {
+		t.Fatal("superseded result should be dropped")
+	}
+	if !w.filtering() {
+		t.Error("job 2 is still in flight")
+	}
+
+	w.filterResults <- &filterResult{seq: 2, filtered: current[:3], positions: map[int][]int{}}
+	w.drainFilterResults()
+	if len(w.filtered) != 3 || w.filtering() {
+		t.Errorf("current result should apply: %d items, filtering=%v", len(w.filtered), w.filtering())
+	}
+}
+
+func TestRequestFilter_SmallInputFiltersInline(t *testing.T) {
+	w := newAsyncTestWindow(t, generateBenchItems(100))
+
+	w.requestFilter("handler")
+	if w.filtering() {
+		t.Error("small inputs should not go through the worker")
+	}
+	if len(w.filtered) != 10 {
+		t.Errorf("expected 10 results immediately, got %d", len(w.filtered))
+	}
+}
+
+func TestFilterDebounce(t *testing.T) {
+	if d := filterDebounce(50000); d != 0 {
+		t.Errorf("50k items: debounce %v, want none", d)
+	}
+	if filterDebounce(1000000) <= filterDebounce(200000) || filterDebounce(200000) == 0 {
+		t.Error("debounce should grow with the item count")
+	}
+}
+
+// countingMatcher is the fuzzy matcher (narrowing included) counting how
+// many items it's asked about.
+type countingMatcher struct {
+	*matcher.FuzzyMatcher
+	calls atomic.Int64
+}
+
+func (m *countingMatcher) Match(query string, item appinput.Item) (bool, []int, int) {
+	m.calls.Add(1)
+	return m.FuzzyMatcher.Match(query, item)
+}
+
+func (m *countingMatcher) MatchOnly(query string, item appinput.Item) bool {
+	m.calls.Add(1)
+	return m.FuzzyMatcher.MatchOnly(query, item)
+}
+
+func (m *countingMatcher) MatchPositions(query string, item appinput.Item) (bool, []int) {
+	m.calls.Add(1)
+	return m.FuzzyMatcher.MatchPositions(query, item)
+}
+
+// Typing narrows from the previous results, and those results match a
+// fresh full pass exactly, ranked or not.
+func TestFilterItems_NarrowingMatchesFullPass(t *testing.T) {
+	items := generateBenchItems(20000)
+	for _, rank := range []bool{false, true} {
+		m := &countingMatcher{FuzzyMatcher: matcher.NewFuzzyMatcher(false, false)}
+		w := newBenchWindow(items)
+		w.matcher, w.rankEnabled, w.ranker = m, rank, ranker.NewRanker()
+		w.filterItems("ha")
+		w.filterItems("hand")
+
+		before := m.calls.Load()
+		w.filterItems("handl")
+		if calls := m.calls.Load() - before; calls != int64(len(items)/10) {
+			t.Errorf("rank=%v: narrowing searched %d items, want the %d previous hits", rank, calls, len(items)/10)
+		}
+
+		fresh := newBenchWindow(items)
+		fresh.rankEnabled, fresh.ranker = rank, ranker.NewRanker()
+		fresh.filterItems("handl")
+		if !reflect.DeepEqual(w.filtered, fresh.filtered) || !reflect.DeepEqual(w.matchPositions, fresh.matchPositions) {
+			t.Errorf("rank=%v: narrowed results differ from a full pass", rank)
+		}
+	}
+}
+
+// Backspacing restores an earlier query's results without matching.
+func TestFilterItems_BackspaceHitsCache(t *testing.T) {
+	items := generateBenchItems(1000)
+	m := &countingMatcher{FuzzyMatcher: matcher.NewFuzzyMatcher(false, false)}
+	w := newBenchWindow(items)
+	w.matcher = m
+	w.filterItems("hand")
+	want := append([]appinput.Item(nil), w.filtered...)
+	w.filterItems("handx")
+
+	before := m.calls.Load()
+	w.filterItems("hand")
+	if calls := m.calls.Load() - before; calls != 0 {
+		t.Errorf("backspace ran %d matches, want a cache hit", calls)
+	}
+	if !reflect.DeepEqual(w.filtered, want) {
+		t.Errorf("restored %d items, want %d", len(w.filtered), len(want))
+	}
+	if len(w.matchPositions[0]) != len("hand") {
+		t.Errorf("restored positions = %v", w.matchPositions[0])
+	}
+}
+
+// Cached results cover only the items seen so far: neither a hit nor a
+// narrowing base may leave later items out.
+func TestFilterItems_CacheCoversNewItems(t *testing.T) {
+	w := newBenchWindow(generateBenchItems(100))
+	w.filterItems("hand")
+	w.filterItems("handx")
+
+	w.items = append(w.items, appinput.Item{Text: "handle late", Raw: "handle late", Index: 100})
+	w.items[len(w.items)-1].Init()
+	w.itemsGeneration++
+	w.filterItems("hand")
+	if n := len(w.filtered); n != 11 {
+		t.Errorf("after growth: %d results for \"hand\", want 11", n)
+	}
+	w.filterItems("handl")
+	if n := len(w.filtered); n != 11 {
+		t.Errorf("after growth: %d results for \"handl\", want 11", n)
+	}
+}
+
+// Switching the ranking scheme must re-rank, not reuse the order cached
+// under the previous one.
+func TestSetRanker_Reranks(t *testing.T) {
+	items := []appinput.Item{
+		{Text: "main/internal/deep/x.go", Index: 0},
+		{Text: "cmd/app/main.go", Index: 1},
+	}
+	for i := range items {
+		items[i].Raw = items[i].Text
+		items[i].Init()
+	}
+	w := newBenchWindow(items)
+	w.matcher = matcher.NewFuzzyMatcher(false, true)
+	w.ranker = ranker.NewRanker()
+	w.rankEnabled = true
+	w.filterItems("main")
+	if got := w.filtered[0].Text; got != "main/internal/deep/x.go" {
+		t.Fatalf("default scheme ranked %q first", got)
+	}
+
+	w.SetRanker(ranker.NewPathRanker())
+	w.filterItems("main")
+	if got := w.filtered[0].Text; got != "cmd/app/main.go" {
+		t.Errorf("path scheme ranked %q first, want the basename match", got)
+	}
+}
+
+// Ranking only the streamed-in hits and merging them into the cached order
+// must follow the ranker's tiebreak chain, like a full pass.
+func TestFilterItems_MergeFollowsTiebreak(t *testing.T) {
+	texts := []string{"foo-long-name", "foo-mid", "a-foo", "foo"}
+	var items []appinput.Item
+	for i, text := range texts {
+		items = append(items, appinput.Item{Text: text, Raw: text, Index: i})
+		items[i].Init()
+	}
+	w := newBenchWindow(items[:2])
+	w.matcher = matcher.NewFuzzyMatcher(false, true)
+	w.ranker = &ranker.Ranker{Tiebreak: []ranker.Criterion{ranker.ByLength}}
+	w.rankEnabled = true
+	w.filterItems("foo")
+
+	w.items = items
+	w.itemsGeneration++
+	w.filterItems("foo")
+	var got []string
+	for _, it := range w.filtered {
+		got = append(got, it.Text)
+	}
+	want := []string{"foo", "a-foo", "foo-mid", "foo-long-name"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged order %v, want %v", got, want)
+	}
+}
+
+// Streamed-in hits merge into the ranking on screen: the ranked rows are
+// a fresh pass's, the unranked rows after them stay where they were, and
+// scrolling to the end still lands on a fresh pass's order.
+func TestFilterItems_MergedRankingKeepsUnrankedRows(t *testing.T) {
+	items := generateBenchItems(4000)
+	newRanked := func(items []appinput.Item) *Window {
+		w := newBenchWindow(items)
+		w.matcher = matcher.NewFuzzyMatcher(false, true)
+		w.ranker = ranker.NewRanker()
+		w.rankEnabled = true
+		w.filterItems("de")
+		return w
+	}
+	w := newRanked(items[:2000])
+	var fresh *Window
+	for n := 2000; n < len(items); n += 500 {
+		if n == 3000 {
+			w.ensureRanked(600) // the user scrolled
+		}
+		before := append([]appinput.Item(nil), w.filtered...)
+		shown := w.shownFilter.ranking.Sorted()
+
+		w.items = items[:n+500]
+		w.itemsGeneration++
+		w.filterItems("de")
+		if w.shownFilter.rankBase == nil {
+			t.Fatalf("at %d: the new hits were ranked from scratch", n)
+		}
+		fresh = newRanked(items[:n+500])
+		fresh.ensureRanked(shown)
+		if len(w.filtered) != len(fresh.filtered) {
+			t.Fatalf("at %d: %d rows, want %d", n, len(w.filtered), len(fresh.filtered))
+		}
+		for i := 0; i < shown; i++ {
+			if w.filtered[i].Index != fresh.filtered[i].Index {
+				t.Fatalf("at %d: ranked row %d is item %d, want %d", n, i, w.filtered[i].Index, fresh.filtered[i].Index)
+			}
+		}
+		for i := shown; i < len(before); i++ {
+			if w.filtered[i].Index != before[i].Index {
+				t.Fatalf("at %d: unranked row %d moved", n, i)
+			}
+		}
+		seen := make(map[int]bool)
+		for _, it := range w.filtered {
+			seen[it.Index] = true
+		}
+		for _, it := range fresh.filtered {
+			if !seen[it.Index] {
+				t.Fatalf("at %d: item %d is missing", n, it.Index)
+			}
+		}
+	}
+
+	w.ensureRanked(len(w.filtered))
+	fresh.ensureRanked(len(fresh.filtered))
+	if !reflect.DeepEqual(w.filtered, fresh.filtered) || !reflect.DeepEqual(w.matchPositions, fresh.matchPositions) {
+		t.Error("fully ranked rows differ from a fresh pass")
+	}
+}
+
+// Approximate (--typos) hits go below the exact ones without --rank too,
+// on a full pass, when the query narrows and when items stream in.
+func TestFilterItems_ApproximateBelowExactUnranked(t *testing.T) {
+	var items []appinput.Item
+	for i, text := range []string{"goose-lanucher", "goose-launcher", "lanucher.go", "launcher.go"} {
+		items = append(items, appinput.Item{Text: text, Raw: text, Index: i})
+		items[i].Init()
+	}
+	w := newBenchWindow(items[:2])
+	w.matcher = matcher.NewFuzzyMatcherWithOptions(matcher.Options{Exact: true, Typos: 1})
+	w.ranker = ranker.NewRanker() // unranked, but its priority weight still applies
+	texts := func() []string {
+		var got []string
+		for _, it := range w.filtered {
+			got = append(got, it.Text)
+		}
+		return got
+	}
+
+	w.filterItems("launche")
+	if got, want := texts(), []string{"goose-launcher", "goose-lanucher"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("full pass: %v, want %v", got, want)
+	}
+	w.filterItems("launcher")
+	if got, want := texts(), []string{"goose-launcher", "goose-lanucher"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("narrowed: %v, want %v", got, want)
+	}
+
+	w.items = items
+	w.itemsGeneration++
+	w.filterItems("launcher")
+	if got, want := texts(), []string{"goose-launcher", "launcher.go", "goose-lanucher", "lanucher.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after streaming: %v, want %v", got, want)
+	}
+}
+
+// A trigram index for the current items narrows a full pass to its
+// candidates; once the items change it no longer applies.
+func TestFilterItems_UsesTrigramIndex(t *testing.T) {
+	items := generateBenchItems(1000)
+	m := &countingMatcher{FuzzyMatcher: matcher.NewFuzzyMatcher(false, true)}
+	w := newBenchWindow(items)
+	w.matcher = m
+	w.filterItems("handler")
+	want := append([]appinput.Item(nil), w.filtered...)
+
+	ix, err := matcher.BuildTrigramIndex(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.trigramIndex.Store(&builtIndex{generation: w.itemsGeneration, index: ix})
+	w.filterCache.clear()
+	w.hasFiltered = false
+	before := m.calls.Load()
+	w.filterItems("handler")
+	if calls := m.calls.Load() - before; calls >= int64(len(items)) {
+		t.Errorf("indexed pass ran %d matches over %d items", calls, len(items))
+	}
+	if !reflect.DeepEqual(w.filtered, want) {
+		t.Errorf("indexed pass found %d items, want %d", len(w.filtered), len(want))
+	}
+
+	w.items = append(w.items, appinput.Item{Text: "handler late", Raw: "handler late", Index: len(items)})
+	w.items[len(w.items)-1].Init()
+	w.itemsGeneration++
+	if w.currentIndex() != nil {
+		t.Fatal("index for an older item generation must not be used")
+	}
+	w.filterCache.clear()
+	w.filterItems("handler")
+	if n := len(w.filtered); n != len(want)+1 {
+		t.Errorf("after growth: %d results, want %d", n, len(want)+1)
+	}
+}
+
+// EndItems on a large input builds the index in the background.
+func TestEndItems_BuildsIndex(t *testing.T) {
+	w := newBenchWindow(generateBenchItems(trigramIndexThreshold))
+	w.itemsGeneration++ // as Configure does
+	w.maybeIndexItems(false)
+	if w.indexCancel != nil {
+		t.Fatal("indexed before the items were complete")
+	}
+	w.EndItems()
+	w.maybeIndexItems(w.itemsComplete.Load())
+	deadline := time.Now().Add(10 * time.Second)
+	for w.currentIndex() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("index never became available")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	w.resetIndex()
+	if w.currentIndex() != nil {
+		t.Error("resetIndex should drop the index")
+	}
+}
+
+// A ranked filter sorts only its first rows up front; rows further down
+// are ranked when the list reaches them and equal a full sort's.
+func TestFilterItems_RanksOnDemand(t *testing.T) {
+	items := generateBenchItems(20000)
+	w := newBenchWindow(items)
+	w.rankEnabled, w.ranker = true, ranker.NewRanker()
+	w.filterItems("handler")
+	if n := w.shownFilter.ranking.Sorted(); n != rankedRows {
+		t.Fatalf("%d rows ranked up front, want %d", n, rankedRows)
+	}
+
+	var matches []ranker.Match
+	for _, it := range items {
+		if ok, pos, score := w.matcher.Match("handler", it); ok {
+			matches = append(matches, ranker.Match{Item: it, Positions: pos, Score: score})
+		}
+	}
+	full := ranker.NewRanker().RankMatches(matches, "handler")
+
+	// Accepting a row past the ranked prefix ranks through it first.
+	row := rankedRows + 10
+	w.list.selected = row
+	if got := w.selectionItems()[0].Raw; got != full[row].Item.Raw {
+		t.Errorf("accepted %q, full sort has %q", got, full[row].Item.Raw)
+	}
+	if n := w.shownFilter.ranking.Sorted(); n <= row || n == len(full) {
+		t.Errorf("%d rows ranked after paging down, want more than %d but not all %d", n, row, len(full))
+	}
+	w.ensureRanked(len(full))
+	for i, m := range full {
+		if w.filtered[i].Raw != m.Item.Raw {
+			t.Fatalf("row %d = %q, full sort has %q", i, w.filtered[i].Raw, m.Item.Raw)
+		}
+	}
+}
+
+// Enter while a job is in flight accepts from the query's results, not
+// from the previous query's rows still on screen.
+func TestAccept_WaitsForFilterInFlight(t *testing.T) {
+	items := generateBenchItems(50000)
+	want := newBenchWindow(items)
+	want.filterItems("handler/4")
+
+	// No worker: the job stays in the mailbox, in flight for as long as
+	// the test needs.
+	w := newBenchWindow(items)
+	w.filterJobs = make(chan *filterJob, 1)
+	w.filterResults = make(chan *filterResult, 1)
+	w.filterCtx, w.filterCancel = context.WithCancel(context.Background())
+
+	// The previous query matched nothing: Enter would print the query.
+	w.filterItems("qqqq")
+	w.searchInput.SetText("handler/4")
+	w.requestFilter("handler/4")
+	if !w.filtering() || len(w.filtered) != 0 {
+		t.Fatalf("want a job in flight over empty results, got filtering=%v, %d rows", w.filtering(), len(w.filtered))
+	}
+	w.accept()
+	if w.selected != want.filtered[0].Raw {
+		t.Errorf("selected %q, want the first match %q", w.selected, want.filtered[0].Raw)
+	}
+
+	// The previous query's rows are on screen: Enter would print one.
+	w.selected = ""
+	w.filterItems("")
+	w.list.selected = 3
+	w.filterCache.clear() // no cached pass to restore instantly
+	w.requestFilter("handler/4")
+	if !w.filtering() || w.filtered[3].Raw == want.filtered[3].Raw {
+		t.Fatalf("want a job in flight over the unfiltered rows, got filtering=%v", w.filtering())
+	}
+	w.accept()
+	if w.selected != want.filtered[3].Raw {
+		t.Errorf("selected %q, want match 3 %q", w.selected, want.filtered[3].Raw)
+	}
+	if w.filtering() {
+		t.Error("the pending job should have been settled")
+	}
+}
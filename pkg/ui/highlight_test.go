@@ -3,6 +3,7 @@ package ui
 import (
 	"image"
 	"image/color"
+	"strings"
 	"testing"
 
 	"gioui.org/font/gofont"
@@ -15,6 +16,7 @@ import (
 
 	appinput "github.com/sam33r/goose-launcher/pkg/input"
 	"github.com/sam33r/goose-launcher/pkg/matcher"
+	"github.com/sam33r/goose-launcher/pkg/ranker"
 )
 
 // TestHighlightMatchesEnabled tests that highlighting is enabled by default
@@ -335,3 +337,92 @@ func TestCommandLineFlagDefault(t *testing.T) {
 		t.Error("NewWindow with highlightMatches=false should disable highlighting")
 	}
 }
+
+// TestSetMatcher_V2HighlightsBestAlignment tests that a matcher installed via
+// SetMatcher drives the highlight positions stored by filterItems.
+func TestSetMatcher_V2HighlightsBestAlignment(t *testing.T) {
+	w := setupTestWindow()
+	w.items = []appinput.Item{mustItem("lib/launcher")}
+	w.filtered = w.items
+
+	w.filterItems("launcher")
+	if got := w.matchPositions[0][0]; got != 0 {
+		t.Fatalf("v1 first position = %d, want greedy 0", got)
+	}
+
+	w.SetMatcher(matcher.NewFuzzyMatcherWithOptions(matcher.Options{Algo: matcher.AlgoV2}))
+	w.filterItems("launcher")
+	if got := w.matchPositions[0][0]; got != 4 {
+		t.Errorf("v2 first position = %d, want 4 (start of basename)", got)
+	}
+}
+
+// TestExtendedQueryMergesHighlightPositions tests that every positive term of
+// an extended query contributes to the row's highlight positions.
+func TestExtendedQueryMergesHighlightPositions(t *testing.T) {
+	w := setupTestWindow()
+	w.items = []appinput.Item{mustItem("go/lib/test"), mustItem("go/lib/main")}
+	w.filtered = w.items
+	w.SetMatcher(matcher.NewFuzzyMatcherWithOptions(matcher.Options{Exact: true, Extended: true}))
+
+	w.filterItems("lib ^go !test")
+
+	if len(w.filtered) != 1 || w.filtered[0].Text != "go/lib/main" {
+		t.Fatalf("filtered = %v, want only go/lib/main", w.filtered)
+	}
+	want := []int{0, 1, 3, 4, 5}
+	got := w.matchPositions[0]
+	if len(got) != len(want) {
+		t.Fatalf("positions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("positions = %v, want %v", got, want)
+		}
+	}
+}
+
+// suffixMatcher is a custom matcher.Matcher used to check the window only
+// depends on the interface.
+type suffixMatcher struct{}
+
+func (suffixMatcher) Match(query string, item appinput.Item) (bool, []int, int) {
+	if !strings.HasSuffix(item.Text, query) {
+		return false, nil, 0
+	}
+	start := len(item.Text) - len(query)
+	positions := make([]int, len(query))
+	for i := range positions {
+		positions[i] = start + i
+	}
+	return true, positions, len(item.Text)
+}
+
+// TestSetMatcher_CustomMatcherDrivesFilterAndHighlight tests that a custom
+// matcher installed via SetMatcher decides the results and the highlights,
+// with and without ranking.
+func TestSetMatcher_CustomMatcherDrivesFilterAndHighlight(t *testing.T) {
+	w := setupTestWindow()
+	w.ranker = ranker.NewRanker()
+	w.items = []appinput.Item{mustItem("a.go"), mustItem("go.mod"), mustItem("main.go")}
+	w.filtered = w.items
+	w.SetMatcher(suffixMatcher{})
+
+	w.filterItems(".go")
+	if len(w.filtered) != 2 {
+		t.Fatalf("filtered = %v, want a.go and main.go", w.filtered)
+	}
+	if got := w.matchPositions[1]; len(got) != 3 || got[0] != 4 {
+		t.Errorf("main.go positions = %v, want [4 5 6]", got)
+	}
+
+	w.rankEnabled = true
+	w.hasFiltered = false
+	w.filterItems(".go")
+	if len(w.filtered) != 2 || w.filtered[0].Text != "a.go" {
+		t.Errorf("ranked = %v, want a.go first", w.filtered)
+	}
+	if got := w.matchPositions[0]; len(got) != 3 || got[0] != 1 {
+		t.Errorf("a.go positions after ranking = %v, want [1 2 3]", got)
+	}
+}
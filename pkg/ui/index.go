@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"context"
+
+	"github.com/sam33r/goose-launcher/pkg/matcher"
+)
+
+// trigramIndexThreshold is the item count from which a complete item set
+// gets a trigram index. Smaller inputs scan in a few tens of milliseconds,
+// not worth the index's memory (~4 bytes per distinct trigram per item,
+// plus a fixed 8 MB).
+const trigramIndexThreshold = 200000
+
+// builtIndex is a finished trigram index and the item generation it
+// covers.
+type builtIndex struct {
+	generation uint64
+	index      *matcher.TrigramIndex
+}
+
+// EndItems tells the window no more items are coming for this request
+// (stdin hit EOF). Safe to call from any goroutine, after the last
+// AppendItems has returned. Large item sets get a trigram index, built in
+// the background, that lets substring queries skip the scan.
+func (w *Window) EndItems() {
+	w.itemsComplete.Store(true)
+	if w.app != nil {
+		w.app.Invalidate()
+	}
+}
+
+// maybeIndexItems starts indexing the current items once they're
+// complete and large enough. Runs on the event loop after
+// drainPendingItems; complete must be read before that drain, or a batch
+// sent just before EndItems could be missing from the index.
+func (w *Window) maybeIndexItems(complete bool) {
+	if !complete || len(w.items) < trigramIndexThreshold || w.indexedGeneration == w.itemsGeneration {
+		return
+	}
+	w.indexedGeneration = w.itemsGeneration
+	if w.indexCancel != nil {
+		w.indexCancel()
+	}
+	var ctx context.Context
+	ctx, w.indexCancel = context.WithCancel(context.Background())
+	gen, items := w.itemsGeneration, w.items
+	go func() {
+		ix, err := matcher.BuildTrigramIndex(ctx, items)
+		if err != nil {
+			return // superseded by a new request
+		}
+		w.trigramIndex.Store(&builtIndex{generation: gen, index: ix})
+	}()
+}
+
+// currentIndex returns the trigram index for the current items, or nil
+// while there is none (not complete, too small, still building, or the
+// items changed since).
+func (w *Window) currentIndex() *matcher.TrigramIndex {
+	b := w.trigramIndex.Load()
+	if b == nil || b.generation != w.itemsGeneration || b.index.Len() != len(w.items) {
+		return nil
+	}
+	return b.index
+}
+
+// resetIndex drops the index and cancels a build in flight. Called when a
+// new request replaces the items.
+func (w *Window) resetIndex() {
+	if w.indexCancel != nil {
+		w.indexCancel()
+		w.indexCancel = nil
+	}
+	w.trigramIndex.Store(nil)
+	w.itemsComplete.Store(false)
+}
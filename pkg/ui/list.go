@@ -28,6 +28,7 @@ type List struct {
 	clicks       []gesture.Click // One per item; grown lazily in Layout
 	scrollToItem int             // Item to scroll to (-1 if no scroll needed)
 	needsScroll  bool            // True if we need to scroll on next layout
+	overlay      string          // Drawn over the right end of the selected row ("" for none)
 
 	// Multi-select state. marked is nil when --multi is off; the gutter
 	// indicator is keyed by Item.Raw so marks survive filter changes (and
@@ -239,6 +240,28 @@ func (l *List) layoutItem(gtx layout.Context, theme *material.Theme, item input.
 						})
 					})
 				}),
+
+				// Layer 3: Overlay (--explain), right-aligned over the text
+				layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+					if !selected || l.overlay == "" {
+						return layout.Dimensions{}
+					}
+					return layout.E.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+						label := material.Body2(theme, l.overlay)
+						label.Color = color.NRGBA{R: 150, G: 150, B: 150, A: 255} // Dim gray, like the count line
+						label.MaxLines = 1
+						return layout.Background{}.Layout(gtx,
+							func(gtx layout.Context) layout.Dimensions {
+								defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+								paint.Fill(gtx.Ops, selectionBgColor)
+								return layout.Dimensions{Size: gtx.Constraints.Min}
+							},
+							func(gtx layout.Context) layout.Dimensions {
+								return layout.Inset{Left: unit.Dp(12)}.Layout(gtx, label.Layout)
+							},
+						)
+					})
+				}),
 			)
 
 			// Register the row's hit area so the per-item gesture.Click
@@ -439,6 +462,18 @@ func (l *List) MoveDown(itemCount int) {
 	}
 }
 
+// rowsNeeded is how many leading rows the list may show or select before
+// the next layout settles: through the cursor, the viewport or a pending
+// scroll target, plus a page of headroom.
+func (l *List) rowsNeeded() int {
+	page := max(l.list.Position.Count, 1)
+	n := max(l.selected+1, l.list.Position.First+page)
+	if l.needsScroll && l.scrollToItem >= 0 {
+		n = max(n, l.scrollToItem+page)
+	}
+	return n + page
+}
+
 // MovePageDown jumps selection down by one page (the number of rows
 // currently visible) and pins the viewport so the new selection is visible
 // with the standard scrollOffset of context above it. Falls back to a single
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"image"
+	"strings"
 	"testing"
 
 	"gioui.org/font/gofont"
@@ -14,6 +15,7 @@ import (
 	"gioui.org/widget/material"
 
 	appinput "github.com/sam33r/goose-launcher/pkg/input"
+	"github.com/sam33r/goose-launcher/pkg/ranker"
 )
 
 // TestListMoveUp tests moving selection up
@@ -224,6 +226,101 @@ func TestWindow_SelectionOutput_MultiEmitsInInputOrder(t *testing.T) {
 	}
 }
 
+// TestWindow_AcceptedItems — the items behind the printed selection and
+// the query they were chosen for are kept for the daemon's selection
+// history and query learning, plugin included.
+func TestWindow_AcceptedItems(t *testing.T) {
+	w := newStreamingTestWindow()
+	w.items = []appinput.Item{
+		{Text: "alpha", Raw: "files:alpha", Plugin: "files"},
+		{Text: "beta", Raw: "apps:beta", Plugin: "apps"},
+		{Text: "gamma", Raw: "files:gamma", Plugin: "files"},
+	}
+	w.filtered = w.items
+	w.multi = true
+	w.list.EnableMulti()
+	w.list.ToggleMark("files:gamma")
+	w.list.ToggleMark("apps:beta")
+	w.searchInput.SetText("a")
+
+	if got := w.AcceptedItems(); got != nil {
+		t.Fatalf("AcceptedItems before accept = %v, want nil", got)
+	}
+	w.selectionOutput()
+	got := w.AcceptedItems()
+	if len(got) != 2 || got[0].Raw != "apps:beta" || got[1].Plugin != "files" {
+		t.Errorf("AcceptedItems = %+v, want apps:beta then files:gamma", got)
+	}
+	if q := w.AcceptedQuery(); q != "a" {
+		t.Errorf("AcceptedQuery = %q, want %q", q, "a")
+	}
+}
+
+// With --explain every printed line carries its score breakdown in a
+// second tab-separated column, and the cursor row gets it as an overlay.
+func TestWindow_ExplainColumn(t *testing.T) {
+	w := newStreamingTestWindow()
+	w.ranker = ranker.NewRanker()
+	w.items = []appinput.Item{
+		{Text: "alpha", Raw: "alpha", Index: 0},
+		{Text: "gamma", Raw: "gamma", Index: 1},
+		{Text: "beta", Raw: "beta", Index: 2},
+	}
+	w.filtered = w.items[:1]
+	w.multi = true
+	w.list.EnableMulti()
+	w.list.ToggleMark("alpha")
+	w.list.ToggleMark("beta") // marked under an earlier query
+	w.searchInput.SetText("al")
+
+	if got := w.selectionOutput(); strings.Contains(got, "\t") {
+		t.Fatalf("selectionOutput without --explain = %q, want bare lines", got)
+	}
+	w.SetExplain(true)
+	lines := strings.Split(w.selectionOutput(), "\n")
+	want := w.ranker.Explain("al", ranker.Match{Item: w.items[0], Positions: []int{0, 1}}).String()
+	if len(lines) != 2 || lines[0] != "alpha\t"+want || lines[1] != "beta\t-" {
+		t.Errorf("selectionOutput = %q, want alpha\\t%s then beta\\t-", lines, want)
+	}
+	if got := w.explainOverlay(); got != want {
+		t.Errorf("overlay = %q, want %q", got, want)
+	}
+	w.explainShown = false // Ctrl+E
+	if got := w.explainOverlay(); got != "" {
+		t.Errorf("hidden overlay = %q, want none", got)
+	}
+}
+
+// With --print0 every output item is NUL-terminated, so items may
+// contain newlines; the typed query is output the same way.
+func TestWindow_Print0(t *testing.T) {
+	w := newStreamingTestWindow()
+	w.items = []appinput.Item{
+		{Text: "one", Raw: "one"},
+		{Text: "two\u2424lines", Raw: "two\nlines"},
+	}
+	w.filtered = w.items
+	w.multi = true
+	w.list.EnableMulti()
+	w.list.ToggleMark("one")
+	w.list.ToggleMark("two\nlines")
+
+	if got := w.selectionOutput(); got != "one\ntwo\nlines" {
+		t.Errorf("selectionOutput = %q, want newline-joined", got)
+	}
+	w.SetPrint0(true)
+	if got := w.selectionOutput(); got != "one\x00two\nlines\x00" {
+		t.Errorf("selectionOutput with --print0 = %q, want each item NUL-terminated", got)
+	}
+	if got := w.queryOutput(); got != "" {
+		t.Errorf("queryOutput with no query = %q, want nothing selected", got)
+	}
+	w.searchInput.SetText("tw")
+	if got := w.queryOutput(); got != "tw\x00" {
+		t.Errorf("queryOutput = %q, want tw NUL-terminated", got)
+	}
+}
+
 // TestWindow_SelectionOutput_MultiNoMarksFallsBackToCursor — fzf parity:
 // pressing Enter with --multi but no marks behaves like single-select.
 func TestWindow_SelectionOutput_MultiNoMarksFallsBackToCursor(t *testing.T) {
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/sam33r/goose-launcher/pkg/ranker"
+)
+
+// runStreamingBench streams n items in batches of 1000 under a typed query,
+// filtering after every batch the way layout does after each drain. With
+// refilter set the result cache is cleared first, forcing the full rescan
+// every batch used to cost.
+func runStreamingBench(b *testing.B, n int, rank, refilter bool) {
+	items := generateBenchItems(n)
+	const batch = 1000
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := newStreamingTestWindow()
+		w.ConfigureEmpty(true, false, rank, false)
+		w.ranker = ranker.NewRanker()
+		for start := 0; start < n; start += batch {
+			w.AppendItems(items[start:min(start+batch, n)])
+			w.drainPendingItems()
+			if refilter {
+				w.filterCache.clear()
+			}
+			w.filterItems("handler")
+		}
+	}
+}
+
+func BenchmarkStreamingFilter_100k(b *testing.B) {
+	runStreamingBench(b, 100000, false, false)
+}
+
+func BenchmarkStreamingFilter_100k_Ranked(b *testing.B) {
+	runStreamingBench(b, 100000, true, false)
+}
+
+func BenchmarkStreamingFilter_100k_Refilter(b *testing.B) {
+	runStreamingBench(b, 100000, false, true)
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"reflect"
 	"sync"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 
 	appinput "github.com/sam33r/goose-launcher/pkg/input"
 	"github.com/sam33r/goose-launcher/pkg/matcher"
+	"github.com/sam33r/goose-launcher/pkg/ranker"
 )
 
 // newStreamingTestWindow builds a Window with the streaming machinery wired
@@ -126,6 +128,70 @@ func TestAppendItems_FilterCacheStableWhenItemsUnchanged(t *testing.T) {
 	}
 }
 
+// Under an unchanged query each streamed batch costs one match per new
+// item, and the results (ranked or not) equal a full pass over everything.
+func TestAppendItems_FiltersOnlyNewItems(t *testing.T) {
+	all := generateBenchItems(3000)
+	for _, rank := range []bool{false, true} {
+		m := &countingMatcher{FuzzyMatcher: matcher.NewFuzzyMatcher(false, false)}
+		w := newStreamingTestWindow()
+		w.ConfigureEmpty(true, false, rank, false)
+		w.SetMatcher(m)
+		w.ranker = ranker.NewRanker()
+
+		for start := 0; start < len(all); start += 500 {
+			w.AppendItems(all[start : start+500])
+			w.drainPendingItems()
+			before := m.calls.Load()
+			w.filterItems("handler")
+			if calls := m.calls.Load() - before; calls != 500 {
+				t.Fatalf("rank=%v: batch at %d matched %d items, want 500", rank, start, calls)
+			}
+		}
+
+		full := newBenchWindow(all)
+		full.rankEnabled, full.ranker = rank, ranker.NewRanker()
+		full.filterItems("handler")
+		// Rankings sort on demand; compare them all the way down.
+		w.ensureRanked(len(w.filtered))
+		full.ensureRanked(len(full.filtered))
+		if !reflect.DeepEqual(w.filtered, full.filtered) || !reflect.DeepEqual(w.matchPositions, full.matchPositions) {
+			t.Errorf("rank=%v: streamed results differ from a full pass (%d vs %d items)", rank, len(w.filtered), len(full.filtered))
+		}
+	}
+}
+
+// The same holds when large streams filter on the worker, which leaves the
+// appending to the event loop.
+func TestAppendItems_AsyncStreamMatchesFullPass(t *testing.T) {
+	all := generateBenchItems(40000)
+	for _, rank := range []bool{false, true} {
+		w := newAsyncTestWindow(t, nil)
+		w.pendingItems = make(chan []appinput.Item, 64)
+		w.rankEnabled, w.ranker = rank, ranker.NewRanker()
+		w.items = all[:asyncFilterThreshold]
+		w.requestFilter("handler")
+		waitForFilter(t, w)
+
+		for start := asyncFilterThreshold; start < len(all); start += 10000 {
+			w.AppendItems(all[start : start+10000])
+			w.drainPendingItems()
+			w.requestFilter("handler")
+			waitForFilter(t, w)
+		}
+
+		full := newBenchWindow(all)
+		full.rankEnabled, full.ranker = rank, ranker.NewRanker()
+		full.filterItems("handler")
+		// Rankings sort on demand; compare them all the way down.
+		w.ensureRanked(len(w.filtered))
+		full.ensureRanked(len(full.filtered))
+		if !reflect.DeepEqual(w.filtered, full.filtered) || !reflect.DeepEqual(w.matchPositions, full.matchPositions) {
+			t.Errorf("rank=%v: streamed results differ from a full pass (%d vs %d items)", rank, len(w.filtered), len(full.filtered))
+		}
+	}
+}
+
 func TestAppendItems_ConcurrentProducers(t *testing.T) {
 	// Multiple goroutines pushing items at once must not lose any. The drain
 	// happens on the consumer (event-loop) goroutine.
@@ -169,3 +235,42 @@ done:
 func mustItem(text string) appinput.Item {
 	return appinput.ParseLine(text, 0, "")
 }
+
+// Items with a priority come first even unranked, with the empty query
+// too; the first prioritized batch reorders what's already shown.
+func TestAppendItems_PriorityOrdersUnranked(t *testing.T) {
+	w := newStreamingTestWindow()
+	w.ConfigureEmpty(true, true, false, false)
+	w.AppendItems([]appinput.Item{
+		appinput.ParseLine("apps   . Calculator", 0, ""),
+		appinput.ParseLine("apps   . Calendar", 1, ""),
+	})
+	w.drainPendingItems()
+	w.filterItems("")
+	w.AppendItems([]appinput.Item{
+		appinput.ParseLine("apps priority=5   . Camera", 2, ""),
+		appinput.ParseLine("apps priority=-1   . Cargo", 3, ""),
+		appinput.ParseLine("apps   . Catalog", 4, ""),
+	})
+	w.drainPendingItems()
+
+	order := func() []string {
+		var texts []string
+		for _, it := range w.filtered {
+			texts = append(texts, it.Text)
+		}
+		return texts
+	}
+	w.filterItems("")
+	if got, want := order(), []string{"Camera", "Calculator", "Calendar", "Catalog", "Cargo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("empty query: %v, want %v", got, want)
+	}
+	w.filterItems("cal")
+	if got, want := order(), []string{"Calculator", "Calendar"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cal: %v, want input order among equals %v", got, want)
+	}
+	w.filterItems("ca")
+	if got := order(); got[0] != "Camera" || got[len(got)-1] != "Cargo" {
+		t.Errorf("ca: %v, want Camera first and Cargo last", got)
+	}
+}
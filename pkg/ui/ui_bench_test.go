@@ -77,13 +77,15 @@ func newBenchWindow(items []appinput.Item) *Window {
 
 // runFilterBench measures the cost of filtering when the query *changes*.
 // We alternate between two queries so the per-frame "query unchanged" cache
-// can't short-circuit the work — that way the bench reflects real keystroke
-// cost, not idle frame cost.
+// can't short-circuit the work, and clear the result cache so neither can
+// backspace restores — that way the bench reflects a full keystroke pass,
+// not idle frame cost.
 func runFilterBench(b *testing.B, n int) {
 	w := newBenchWindow(generateBenchItems(n))
 	queries := [2]string{"handler", "handlex"}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
+		w.filterCache.clear()
 		w.filterItems(queries[i&1])
 	}
 }
@@ -260,6 +262,7 @@ func BenchmarkMemoryAllocation_FilterItems(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
+		w.filterCache.clear()
 		w.filterItems(queries[i&1])
 	}
 }
@@ -280,6 +283,26 @@ func BenchmarkSearchLatency_Progressive(b *testing.B) {
 
 	queries := []string{"h", "ha", "han", "hand", "handl", "handle", "handler"}
 
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Each keystroke narrows the previous one's results; start every
+		// round cold so "h" is a full pass.
+		w.filterCache.clear()
+		for _, query := range queries {
+			w.filterItems(query)
+		}
+	}
+}
+
+// BenchmarkSearchLatency_Backspace measures deleting a query one character
+// at a time after typing it: every step is a result-cache hit.
+func BenchmarkSearchLatency_Backspace(b *testing.B) {
+	w := newBenchWindow(generateBenchItems(100000))
+	queries := []string{"handler", "handle", "handl", "hand", "han", "ha", "h"}
+	for i := len(queries) - 1; i >= 0; i-- {
+		w.filterItems(queries[i])
+	}
+
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		for _, query := range queries {
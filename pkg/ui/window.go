@@ -1,11 +1,13 @@
 package ui
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"image/color"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gioui.org/app"
@@ -60,10 +62,16 @@ type Window struct {
 	matchPositions   map[int][]int     // Mapping of filtered index to match positions
 	list             *List
 	searchInput      *Input             // Search input field
-	matcher          *matcher.FuzzyMatcher // Fuzzy matcher
+	matcher          matcher.Matcher    // Item matcher (fuzzy/exact/regex or a registered custom one)
 	ranker           *ranker.Ranker        // Match ranker/scorer
 	rankEnabled      bool                  // Whether to rank results
+	prioritized      bool                  // Some item has a priority; results are in priority order even unranked
+	explain          bool                  // --explain: print accepted lines with their score breakdown
+	explainShown     bool                  // Overlay the cursor row's score breakdown (Ctrl+E toggles)
+	print0           bool                  // --print0: terminate each output item with NUL instead of joining with "\n"
 	selected         string // Selected item (empty if none)
+	accepted         []input.Item // Items behind selected; nil when it's the query text
+	acceptedQuery    string       // Query the accepted items were chosen for
 	cancelled        bool   // True if user pressed ESC
 	keyTag           bool   // Tag for key events
 	highlightMatches bool   // Whether to highlight matching text
@@ -77,10 +85,15 @@ type Window struct {
 	// that costs ~140 ms per redraw.
 	lastQuery   string
 	hasFiltered bool
+	// queryErr is set while the query can't be matched (an invalid
+	// --regex pattern). filtered keeps the last good results meanwhile.
+	queryErr error
 	// filteredOwned is the backing slice we control. w.filtered may alias
 	// w.items when the query is empty; we keep filteredOwned separate so
 	// subsequent non-empty queries don't write through into w.items.
 	filteredOwned []input.Item
+	// rankInput is the reusable buffer of matches handed to the ranker.
+	rankInput []ranker.Match
 
 	// Streaming-stdin support. Producers (the daemon's chunk-reader
 	// goroutine) push batches into pendingItems; the event-loop goroutine
@@ -92,6 +105,35 @@ type Window struct {
 	itemsGeneration        uint64
 	lastFilteredGeneration uint64
 
+	// Async filtering. requestFilter hands (query, itemsGeneration) jobs to
+	// runFilterWorker through the one-slot filterJobs mailbox; finished
+	// results come back on filterResults and are applied at the top of the
+	// next layout pass, mirroring pendingItems. Sequence numbers drop
+	// results that a newer query, matcher or request has superseded.
+	filterJobs    chan *filterJob
+	filterResults chan *filterResult
+	filterCtx     context.Context    // cancelled when in-flight jobs are superseded
+	filterCancel  context.CancelFunc // cancels filterCtx
+	filterSeq     uint64             // seq of the most recently submitted job
+	filterBaseSeq uint64             // results with a lower seq are stale
+	appliedSeq    uint64             // seq of the result on screen
+
+	// filterCache remembers recent passes so backspacing is instant, a
+	// query that extends a cached one only searches that one's hits, and
+	// streamed items are matched once. shownFilter is the pass on screen;
+	// nil for the empty query.
+	filterCache filterCache
+	shownFilter *filterEntry
+
+	// Trigram index (see index.go). itemsComplete is set by EndItems;
+	// indexedGeneration is the item generation last handed to the index
+	// builder, which publishes into trigramIndex. An index only applies
+	// while its generation is current.
+	itemsComplete     atomic.Bool
+	indexedGeneration uint64
+	indexCancel       context.CancelFunc
+	trigramIndex      atomic.Pointer[builtIndex]
+
 	// Daemon-mode signaling. nil channels are fine (no daemon waiting); the
 	// non-blocking sends elsewhere handle that case.
 	requestDone     chan struct{} // closed when current request completes (selection or cancel)
@@ -165,8 +207,12 @@ func newWindowShell() *Window {
 		firstFrame:     true,
 		firstFrameOnce: make(chan struct{}),
 		pendingItems:   make(chan []input.Item, 64),
+		filterJobs:     make(chan *filterJob, 1),
+		filterResults:  make(chan *filterResult, 1),
 	}
+	window.filterCtx, window.filterCancel = context.WithCancel(context.Background())
 	window.searchInput.Focus()
+	go window.runFilterWorker()
 
 	if BenchmarkMode {
 		window.metrics.WindowCreationEnd = time.Now()
@@ -187,6 +233,8 @@ func (w *Window) Configure(items []input.Item, highlightMatches, exactMode, rank
 	w.items = items
 	w.filtered = items
 	w.itemsGeneration++
+	w.itemsComplete.Store(true)
+	w.notePriorities(items)
 }
 
 // ConfigureEmpty prepares the window for a streaming request. Same as
@@ -207,8 +255,21 @@ func (w *Window) configureCommon(highlightMatches, exactMode, rankEnabled, multi
 	for k := range w.matchPositions {
 		delete(w.matchPositions, k)
 	}
-	w.matcher = matcher.NewFuzzyMatcher(false, exactMode)
+	// Smart case by default: lowercase queries fold case, a query with an
+	// uppercase letter matches case-sensitively. Diacritics are ignored
+	// ("cafe" finds "Café"). Daemon requests override this (and the other
+	// matcher flags) via SetMatcher.
+	w.matcher = matcher.NewFuzzyMatcherWithOptions(matcher.Options{
+		Case:      matcher.CaseSmart,
+		Exact:     exactMode,
+		Normalize: true,
+	})
+	w.ranker = ranker.NewRanker()
 	w.rankEnabled = rankEnabled
+	w.prioritized = false
+	w.explain = false
+	w.explainShown = false
+	w.print0 = false
 	w.highlightMatches = highlightMatches
 	w.multi = multi
 	if multi {
@@ -218,11 +279,19 @@ func (w *Window) configureCommon(highlightMatches, exactMode, rankEnabled, multi
 
 	// Reset per-request runtime state.
 	w.selected = ""
+	w.accepted = nil
+	w.acceptedQuery = ""
 	w.cancelled = false
 	w.lastQuery = ""
 	w.hasFiltered = false
+	w.queryErr = nil
+	w.supersedeFilterJobs()
+	w.filterCache.clear()
+	w.shownFilter = nil
+	w.resetIndex()
 	w.lastFilteredGeneration = 0
 	w.filteredOwned = w.filteredOwned[:0]
+	w.rankInput = w.rankInput[:0]
 
 	// Drop any chunks left over from a previous request (defensive — the
 	// daemon already serializes via workMu and waits for the chunk-reader
@@ -254,6 +323,54 @@ drained:
 	w.requestDoneOnce = &sync.Once{}
 }
 
+// SetMatcher replaces the matcher configureCommon installed. Call after
+// Configure/ConfigureEmpty and before the window is shown; the daemon uses
+// it to apply per-request matcher flags (e.g. --algo, --matcher) that the
+// positional Configure arguments don't cover.
+func (w *Window) SetMatcher(m matcher.Matcher) {
+	w.matcher = m
+	w.hasFiltered = false
+	w.filterCache.clear()
+	w.shownFilter = nil
+}
+
+// SetRanker replaces the ranker configureCommon installed (the default
+// scheme). Like SetMatcher, call it after Configure/ConfigureEmpty; the
+// daemon uses it for --scheme.
+func (w *Window) SetRanker(r *ranker.Ranker) {
+	w.ranker = r
+	w.hasFiltered = false
+	w.filterCache.clear()
+	w.shownFilter = nil
+}
+
+// toggleRegex flips the matcher between regex and its configured
+// exact/fuzzy mode, keeping every other matcher option. Bound to Ctrl+R.
+// Custom (registered) matchers have no regex variant, so it's a no-op.
+func (w *Window) toggleRegex() {
+	fm, ok := w.matcher.(*matcher.FuzzyMatcher)
+	if !ok {
+		return
+	}
+	opts := fm.Options()
+	opts.Regex = !opts.Regex
+	w.SetMatcher(matcher.NewFuzzyMatcherWithOptions(opts))
+}
+
+// regexMode reports whether the current matcher treats queries as regular
+// expressions; drives the "(regex)" marker next to the count.
+func (w *Window) regexMode() bool {
+	fm, ok := w.matcher.(*matcher.FuzzyMatcher)
+	return ok && fm.Options().Regex
+}
+
+// approximate reports whether the current matcher can return approximate
+// (--typos) hits, which go below the exact ones even when unranked.
+func (w *Window) approximate() bool {
+	fm, ok := w.matcher.(*matcher.FuzzyMatcher)
+	return ok && fm.Options().Typos > 0
+}
+
 // signalRequestDone closes w.requestDone exactly once for the current request.
 // Safe to call from any goroutine. No-op if no request is currently active.
 func (w *Window) signalRequestDone() {
@@ -294,6 +411,7 @@ func (w *Window) drainPendingItems() {
 		select {
 		case batch := <-w.pendingItems:
 			w.items = append(w.items, batch...)
+			w.notePriorities(batch)
 			drained = true
 		default:
 			if drained {
@@ -304,6 +422,24 @@ func (w *Window) drainPendingItems() {
 	}
 }
 
+// notePriorities switches to priority order once an item with a priority
+// arrives. Results so far were filtered without it, so they're redone.
+func (w *Window) notePriorities(items []input.Item) {
+	if w.prioritized {
+		return
+	}
+	for i := range items {
+		if items[i].Priority != 0 {
+			w.prioritized = true
+			w.hasFiltered = false
+			w.supersedeFilterJobs()
+			w.filterCache.clear()
+			w.shownFilter = nil
+			return
+		}
+	}
+}
+
 // GioWindow exposes the underlying *app.Window so callers can call
 // Invalidate() to wake the event loop after externally showing the window.
 func (w *Window) GioWindow() *app.Window { return w.app }
@@ -441,18 +577,76 @@ func (w *Window) toggleCurrentMark() {
 // Raw text. In --multi mode with marks, it's every marked item joined by
 // newlines, in original stdin order so the output is deterministic and
 // independent of mark order. With --multi but no marks, falls back to the
-// cursor row (matches fzf).
+// cursor row (matches fzf). The items themselves and the query they were
+// chosen for are kept for AcceptedItems and AcceptedQuery, which the
+// daemon's selection history and query learning read.
 //
 // Caller must guarantee len(w.filtered) > 0.
 func (w *Window) selectionOutput() string {
-	cursor := w.filtered[w.list.Selected()].Raw
+	w.accepted = w.selectionItems()
+	w.acceptedQuery = w.searchInput.Text()
+	out := make([]string, len(w.accepted))
+	for i, it := range w.accepted {
+		out[i] = w.outputLine(it)
+	}
+	return w.joinOutput(out...)
+}
+
+// accept is what Enter does. In --multi mode it emits every marked item
+// (newline-joined, in original stdin order); otherwise the current cursor
+// row. fzf falls back to the cursor when nothing is marked — we mirror
+// that. With no matches but text in the input, the query text is output
+// (like Shift+Enter).
+func (w *Window) accept() {
+	w.settleFilter()
+	if len(w.filtered) > 0 {
+		w.selected = w.selectionOutput()
+	} else if w.searchInput.Text() != "" {
+		w.selected = w.queryOutput()
+	}
+}
+
+// queryOutput is the typed query as output (Shift+Enter, or Enter with no
+// matches); "" when there's no query.
+func (w *Window) queryOutput() string {
+	query := w.searchInput.Text()
+	if query == "" {
+		return ""
+	}
+	return w.joinOutput(query)
+}
+
+// joinOutput puts the output items together: joined by newlines, or each
+// terminated by a NUL with --print0, so items may contain newlines.
+func (w *Window) joinOutput(items ...string) string {
+	if !w.print0 {
+		return strings.Join(items, "\n")
+	}
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString(item)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// SetPrint0 turns --print0 on or off. Like SetRanker, call it after
+// Configure/ConfigureEmpty.
+func (w *Window) SetPrint0(on bool) {
+	w.print0 = on
+}
+
+// selectionItems returns the items selectionOutput prints.
+func (w *Window) selectionItems() []input.Item {
+	w.ensureRanked(w.list.Selected() + 1)
+	cursor := []input.Item{w.filtered[w.list.Selected()]}
 	if !w.multi || w.list.MarkedCount() == 0 {
 		return cursor
 	}
-	out := make([]string, 0, w.list.MarkedCount())
+	out := make([]input.Item, 0, w.list.MarkedCount())
 	for _, it := range w.items {
 		if w.list.IsMarked(it.Raw) {
-			out = append(out, it.Raw)
+			out = append(out, it)
 		}
 	}
 	if len(out) == 0 {
@@ -460,7 +654,19 @@ func (w *Window) selectionOutput() string {
 		// a different set after marking (rare). Fall back to the cursor.
 		return cursor
 	}
-	return strings.Join(out, "\n")
+	return out
+}
+
+// AcceptedItems returns the items the completed request selected, nil
+// when it was cancelled or the query text was accepted instead. Valid once
+// WaitForSelection returns.
+func (w *Window) AcceptedItems() []input.Item {
+	return w.accepted
+}
+
+// AcceptedQuery returns the query AcceptedItems were chosen for.
+func (w *Window) AcceptedQuery() string {
+	return w.acceptedQuery
 }
 
 // Cancel dismisses the current request as if the user pressed ESC. Safe to
@@ -479,21 +685,24 @@ func (w *Window) Cancel() {
 	w.signalRequestDone()
 }
 
-// filterItems filters items based on the search query.
+// filterItems filters items based on the search query, synchronously.
 // No-op when called repeatedly with the same query and the same items
 // (the layout pass calls this on every frame; we don't want to re-walk a
 // million items on idle redraws). When stdin is streaming, itemsGeneration
-// bumps on growth and forces a re-filter.
+// bumps on growth and forces a pass over just the new items. layout goes through
+// requestFilter, which moves large inputs off the event loop.
 func (w *Window) filterItems(query string) {
-	if w.hasFiltered && query == w.lastQuery && w.lastFilteredGeneration == w.itemsGeneration {
+	if !w.filterStale(query) {
+		return
+	}
+	w.markFiltered(query)
+	if !w.checkQuery(query) {
 		return
 	}
-	w.lastQuery = query
-	w.hasFiltered = true
-	w.lastFilteredGeneration = w.itemsGeneration
 
-	if query == "" {
+	if query == "" && !w.prioritized {
 		w.filtered = w.items
+		w.shownFilter = nil
 		// Reuse the existing map allocation when possible to avoid GC churn.
 		for k := range w.matchPositions {
 			delete(w.matchPositions, k)
@@ -501,59 +710,47 @@ func (w *Window) filterItems(query string) {
 		return
 	}
 
-	// Whether downstream consumers actually need positions; skipping the
-	// allocation cuts ~1 alloc/match for the --highlight-matches=false path.
-	needPositions := w.highlightMatches || w.rankEnabled
+	// Backspacing to a query we've seen restores it from the cache;
+	// extending one only searches its results (see newFilterJob).
+	if w.showCachedFilter(query) {
+		return
+	}
 
 	// Reuse the filtered slice's backing array across frames so progressive
 	// typing doesn't reallocate. Always go through filteredOwned — we never
-	// want to write through w.filtered when it's aliased to w.items.
-	filtered := w.filteredOwned[:0]
+	// want to write through w.filtered when it's aliased to w.items. The
+	// positions map is reused too; clearing is cheaper than a fresh
+	// allocation when the result-set size doesn't change much.
+	entry, rankInput, _ := w.newFilterJob(query).run(w.rankInput[:0])
+	w.rankInput = rankInput
+	w.showFilterEntry(entry)
+	w.filterCache.add(entry)
+}
 
-	// Reuse the positions map; clearing is cheaper than a fresh allocation
-	// for the common case where the result-set size doesn't change much.
-	for k := range w.matchPositions {
-		delete(w.matchPositions, k)
-	}
+// filterStale reports whether the on-screen results (or the job computing
+// them) are for a different query, item set or matcher.
+func (w *Window) filterStale(query string) bool {
+	return !w.hasFiltered || query != w.lastQuery || w.lastFilteredGeneration != w.itemsGeneration
+}
 
-	filteredIdx := 0
-	for _, item := range w.items {
-		var (
-			match     bool
-			positions []int
-		)
-		if needPositions {
-			match, positions = w.matcher.Match(query, item)
-		} else {
-			match = w.matcher.MatchOnly(query, item)
-		}
-		if match {
-			filtered = append(filtered, item)
-			if needPositions {
-				w.matchPositions[filteredIdx] = positions
-			}
-			filteredIdx++
-		}
-	}
-	w.filteredOwned = filtered
-	w.filtered = filtered
+// markFiltered records that results for query and the current items are
+// now on screen or on their way.
+func (w *Window) markFiltered(query string) {
+	w.lastQuery = query
+	w.hasFiltered = true
+	w.lastFilteredGeneration = w.itemsGeneration
+}
 
-	// Optional ranking pass.
-	if w.rankEnabled && len(w.filtered) > 0 {
-		scores := w.ranker.RankMatches(w.filtered, w.matchPositions, query)
-		filtered = filtered[:0]
-		for k := range w.matchPositions {
-			delete(w.matchPositions, k)
-		}
-		for i, score := range scores {
-			filtered = append(filtered, score.Item)
-			if needPositions {
-				w.matchPositions[i] = score.Positions
-			}
-		}
-		w.filteredOwned = filtered
-		w.filtered = filtered
+// checkQuery reports whether query can be filtered. An uncompilable regex
+// (usually a half-typed one) would match nothing; keep the previous
+// results on screen and surface the error instead.
+func (w *Window) checkQuery(query string) bool {
+	if err := matcher.QueryError(w.matcher, query); err != nil {
+		w.queryErr = err
+		return false
 	}
+	w.queryErr = nil
+	return true
 }
 
 // layout renders the window contents
@@ -565,7 +762,11 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 
 	// Drain any items that streamed in since the last frame. Must happen
 	// before filtering so the new items participate in this frame's render.
+	complete := w.itemsComplete.Load()
 	w.drainPendingItems()
+	w.maybeIndexItems(complete)
+	// Pick up results the filter worker finished since the last frame.
+	w.drainFilterResults()
 
 	// Register for keyboard events FIRST (cover entire window area)
 	// This ensures window-level keys are registered before editor widget
@@ -653,6 +854,30 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 		}
 	}
 
+	// Process Ctrl+R (toggle regex mode)
+	for {
+		ev, ok := gtx.Event(key.Filter{Name: "R", Required: key.ModCtrl})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press {
+			w.toggleRegex()
+			gtx.Execute(op.InvalidateCmd{})
+		}
+	}
+
+	// Process Ctrl+E (toggle the --explain overlay)
+	for {
+		ev, ok := gtx.Event(key.Filter{Name: "E", Required: key.ModCtrl})
+		if !ok {
+			break
+		}
+		if e, ok := ev.(key.Event); ok && e.State == key.Press && w.explain {
+			w.explainShown = !w.explainShown
+			gtx.Execute(op.InvalidateCmd{})
+		}
+	}
+
 	// Process Shift+Return key (for outputting query)
 	for {
 		ev, ok := gtx.Event(key.Filter{Name: key.NameReturn, Required: key.ModShift})
@@ -660,7 +885,8 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 			break
 		}
 		if e, ok := ev.(key.Event); ok && e.State == key.Press {
-			w.selected = w.searchInput.Text()
+			w.selected = w.queryOutput()
+			w.accepted = nil
 		}
 	}
 
@@ -679,16 +905,10 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 			}
 			if e.Modifiers.Contain(key.ModShift) {
 				// Shift+Enter: Use current query as selection
-				w.selected = w.searchInput.Text()
-			} else if len(w.filtered) > 0 {
-				// Regular Enter: in --multi mode emit every marked item
-				// (newline-joined, in original stdin order); otherwise the
-				// current cursor row. fzf falls back to the cursor when
-				// nothing is marked — we mirror that.
-				w.selected = w.selectionOutput()
-			} else if w.searchInput.Text() != "" {
-				// No matches but text in input: output the query text (like Shift+Enter)
-				w.selected = w.searchInput.Text()
+				w.selected = w.queryOutput()
+				w.accepted = nil
+			} else {
+				w.accept()
 			}
 		}
 	}
@@ -713,6 +933,7 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 		if e, ok := ev.(key.Event); ok && e.State == key.Press {
 			if len(w.filtered) > 0 {
 				idx := w.list.Selected()
+				w.ensureRanked(idx + 1)
 				w.searchInput.SetText(w.filtered[idx].Raw)
 				gtx.Execute(op.InvalidateCmd{})
 			}
@@ -794,7 +1015,8 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 
 	// Render everything
 	dims := layout.Flex{Axis: layout.Vertical}.Layout(gtx,
-		// Item count display (fzf-style: "X/Y", or "M/X/Y" when --multi).
+		// Item count display (fzf-style: "X/Y", or "M/X/Y" when --multi),
+		// plus the regex mode marker and any query error.
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			var countText string
 			if w.multi {
@@ -802,9 +1024,26 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 			} else {
 				countText = fmt.Sprintf("  %d/%d", len(w.filtered), len(w.items))
 			}
+			if w.regexMode() {
+				countText += "  (regex)"
+			}
+			if w.filtering() {
+				countText += "  filtering…"
+			}
 			label := material.Body1(w.theme, countText)
 			label.Color = color.NRGBA{R: 150, G: 150, B: 150, A: 255} // Dim gray
-			return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, label.Layout)
+			return layout.Inset{Top: unit.Dp(4), Bottom: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				if w.queryErr == nil {
+					return label.Layout(gtx)
+				}
+				errLabel := material.Body1(w.theme, "  "+w.queryErr.Error())
+				errLabel.Color = color.NRGBA{R: 230, G: 100, B: 100, A: 255} // Muted red
+				errLabel.MaxLines = 1
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+					layout.Rigid(label.Layout),
+					layout.Flexed(1, errLabel.Layout),
+				)
+			})
 		}),
 
 		// Search input
@@ -814,13 +1053,21 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 
 		// Items list
 		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
-			return w.list.Layout(gtx, w.theme, w.filtered, w.matchPositions, w.highlightMatches)
+			// Rankings are sorted only as far as the list reaches. A wheel
+			// scroll past that during layout gets its rows next frame.
+			w.ensureRanked(w.list.rowsNeeded())
+			w.list.overlay = w.explainOverlay()
+			dims := w.list.Layout(gtx, w.theme, w.filtered, w.matchPositions, w.highlightMatches)
+			if w.ensureRanked(w.list.rowsNeeded()) {
+				gtx.Execute(op.InvalidateCmd{})
+			}
+			return dims
 		}),
 	)
 
 	// After layout, get current query and update filtering for next frame
 	query := w.searchInput.Text()
-	w.filterItems(query)
+	w.requestFilter(query)
 
 	// Check for item acceptance (double-click). Single clicks only move
 	// the highlight; the request only completes when the user double-clicks
@@ -833,7 +1080,9 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 		if w.multi && w.list.MarkedCount() > 0 && w.list.IsMarked(w.filtered[acceptedIdx].Raw) {
 			w.selected = w.selectionOutput()
 		} else {
-			w.selected = w.filtered[acceptedIdx].Raw
+			w.selected = w.joinOutput(w.outputLine(w.filtered[acceptedIdx]))
+			w.accepted = []input.Item{w.filtered[acceptedIdx]}
+			w.acceptedQuery = w.searchInput.Text()
 		}
 		w.list.ResetAccepted()
 	}
@@ -852,10 +1101,8 @@ func (w *Window) layout(gtx layout.Context) layout.Dimensions {
 
 		// Check for submit event (Enter key from editor)
 		if _, ok := ev.(widget.SubmitEvent); ok {
-			if w.selected == "" && len(w.filtered) > 0 {
-				w.selected = w.selectionOutput()
-			} else if w.selected == "" && w.searchInput.Text() != "" {
-				w.selected = w.searchInput.Text()
+			if w.selected == "" {
+				w.accept()
 			}
 		}
 	}
@@ -568,3 +568,55 @@ func TestShiftEnterKeySelection(t *testing.T) {
 		t.Errorf("after Shift+Enter with no matches, selected = %q, want %q", w.selected, "Matches Nothing")
 	}
 }
+
+// TestFilterItems_InvalidRegexKeepsResults tests that a half-typed regex
+// leaves the previous results on screen and records the compile error.
+func TestFilterItems_InvalidRegexKeepsResults(t *testing.T) {
+	w := setupTestWindow()
+	w.SetMatcher(matcher.NewFuzzyMatcherWithOptions(matcher.Options{Regex: true}))
+
+	w.filterItems("item [12]")
+	if len(w.filtered) != 2 {
+		t.Fatalf("expected 2 filtered items, got %d", len(w.filtered))
+	}
+
+	w.filterItems("item [12")
+	if w.queryErr == nil {
+		t.Fatal("expected queryErr for an unterminated character class")
+	}
+	if len(w.filtered) != 2 {
+		t.Errorf("invalid regex should keep previous results, got %d items", len(w.filtered))
+	}
+
+	w.filterItems("item [12]$")
+	if w.queryErr != nil {
+		t.Errorf("queryErr should clear once the regex compiles, got %v", w.queryErr)
+	}
+}
+
+// TestToggleRegex tests that Ctrl+R's toggle swaps only the regex mode and
+// forces a re-filter of the unchanged query.
+func TestToggleRegex(t *testing.T) {
+	w := setupTestWindow()
+	w.SetMatcher(matcher.NewFuzzyMatcherWithOptions(matcher.Options{Exact: true, Extended: true}))
+
+	w.filterItems("item [45]")
+	if len(w.filtered) != 0 {
+		t.Fatalf("expected no literal matches, got %d", len(w.filtered))
+	}
+
+	w.toggleRegex()
+	opts := w.matcher.(*matcher.FuzzyMatcher).Options()
+	if !opts.Regex || !opts.Exact || !opts.Extended {
+		t.Fatalf("options after toggle = %+v, want Regex with Exact/Extended kept", opts)
+	}
+	w.filterItems("item [45]")
+	if len(w.filtered) != 2 {
+		t.Errorf("expected 2 regex matches, got %d", len(w.filtered))
+	}
+
+	w.toggleRegex()
+	if w.regexMode() {
+		t.Error("second toggle should turn regex off")
+	}
+}